@@ -0,0 +1,167 @@
+package avltree
+
+// Union adds all elements of other map to current map.
+//
+// For keys that are present in both maps, value is determined by
+// resolve, called with values of current and other map. Other map
+// is not modified. Union takes O(k log(n/k + 1)) time, where k is
+// the size of the smallest map.
+func (m *Map[K, V]) Union(other *Map[K, V], resolve func(key K, x, y V) V) {
+	if m == other {
+		other = m.Clone()
+	}
+	m.version++
+	m.root = m.union(m.root, other.root, resolve)
+	m.len = m.root.getSize()
+}
+
+// Intersection removes elements which keys are missing in other map.
+//
+// Values of remaining elements are determined by resolve, called
+// with values of current and other map. Other map is not modified.
+func (m *Map[K, V]) Intersection(other *Map[K, V], resolve func(key K, x, y V) V) {
+	if m == other {
+		other = m.Clone()
+	}
+	m.version++
+	m.root = m.intersection(m.root, other.root, resolve)
+	m.len = m.root.getSize()
+}
+
+// Difference removes elements which keys are present in other map.
+//
+// Other map is not modified.
+func (m *Map[K, V]) Difference(other *Map[K, V]) {
+	if m == other {
+		m.root, m.len = nil, 0
+		m.version++
+		return
+	}
+	m.version++
+	m.root = m.difference(m.root, other.root)
+	m.len = m.root.getSize()
+}
+
+// SymmetricDifference removes elements which keys are present in
+// other map and adds elements of other map which keys are missing
+// in current map.
+//
+// Other map is not modified.
+func (m *Map[K, V]) SymmetricDifference(other *Map[K, V]) {
+	if m == other {
+		m.root, m.len = nil, 0
+		m.version++
+		return
+	}
+	m.version++
+	m.root = m.symmetricDifference(m.root, other.root)
+	m.len = m.root.getSize()
+}
+
+func (m *Map[K, V]) union(
+	n, o *Node[K, V], resolve func(key K, x, y V) V,
+) *Node[K, V] {
+	if o == nil {
+		return n
+	}
+	if n == nil {
+		return o.clone()
+	}
+	l, e, r := m.splitFind(n, o.key)
+	if e != nil {
+		e.value = resolve(e.key, e.value, o.value)
+	} else {
+		e = &Node[K, V]{key: o.key, value: o.value}
+	}
+	l = m.union(l, o.left, resolve)
+	r = m.union(r, o.right, resolve)
+	return m.join(l, e, r)
+}
+
+func (m *Map[K, V]) intersection(
+	n, o *Node[K, V], resolve func(key K, x, y V) V,
+) *Node[K, V] {
+	if n == nil || o == nil {
+		return nil
+	}
+	l, e, r := m.splitFind(n, o.key)
+	l = m.intersection(l, o.left, resolve)
+	r = m.intersection(r, o.right, resolve)
+	if e == nil {
+		return m.join2(l, r)
+	}
+	e.value = resolve(e.key, e.value, o.value)
+	return m.join(l, e, r)
+}
+
+func (m *Map[K, V]) difference(n, o *Node[K, V]) *Node[K, V] {
+	if n == nil || o == nil {
+		return n
+	}
+	l, _, r := m.splitFind(n, o.key)
+	l = m.difference(l, o.left)
+	r = m.difference(r, o.right)
+	return m.join2(l, r)
+}
+
+func (m *Map[K, V]) symmetricDifference(n, o *Node[K, V]) *Node[K, V] {
+	if o == nil {
+		return n
+	}
+	if n == nil {
+		return o.clone()
+	}
+	l, e, r := m.splitFind(n, o.key)
+	l = m.symmetricDifference(l, o.left)
+	r = m.symmetricDifference(r, o.right)
+	if e != nil {
+		return m.join2(l, r)
+	}
+	return m.join(l, &Node[K, V]{key: o.key, value: o.value}, r)
+}
+
+// splitFind splits tree n into trees with node.key < key and with
+// node.key > key, and returns detached node with node.key == key.
+//
+// If tree contains several nodes with node.key == key, only one of them
+// is detached and the rest stay in l or r.
+func (m *Map[K, V]) splitFind(n *Node[K, V], key K) (l, e, r *Node[K, V]) {
+	if n == nil {
+		return nil, nil, nil
+	}
+	nl, nr := n.left.detach(), n.right.detach()
+	if m.less(n.key, key) {
+		rl, e, rr := m.splitFind(nr, key)
+		return m.join(nl, n, rl), e, rr
+	}
+	if m.less(key, n.key) {
+		ll, e, lr := m.splitFind(nl, key)
+		return ll, e, m.join(lr, n, nr)
+	}
+	n.left, n.right, n.parent = nil, nil, nil
+	return nl, n, nr
+}
+
+// join2 links trees l and r and returns root of resulting tree.
+func (m *Map[K, V]) join2(l, r *Node[K, V]) *Node[K, V] {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+	k := l
+	for k.right != nil {
+		k = k.right
+	}
+	if p := k.parent; p != nil {
+		p.right = k.left
+		if k.left != nil {
+			k.left.parent = p
+		}
+		l = m.rebalanceUp(p)
+	} else {
+		l = k.left.detach()
+	}
+	return m.join(l, k, r)
+}
@@ -0,0 +1,244 @@
+package avltree
+
+// Monoid represents associative operation with identity element
+// that is used for aggregation of map elements.
+type Monoid[K, V, A any] struct {
+	// Identity is an identity element of Combine.
+	Identity A
+	// Combine combines two aggregates. It should be associative,
+	// but it is not required to be commutative: x always aggregates
+	// elements with smaller keys than y.
+	Combine func(x, y A) A
+	// Project returns aggregate of single element.
+	Project func(key K, value V) A
+}
+
+type augmentedValue[V, A any] struct {
+	value     V
+	aggregate A
+}
+
+// AugmentedNode represents element of augmented map.
+type AugmentedNode[K, V, A any] Node[K, augmentedValue[V, A]]
+
+// Key returns node key.
+func (n *AugmentedNode[K, V, A]) Key() K {
+	return n.key
+}
+
+// Value returns node value.
+func (n *AugmentedNode[K, V, A]) Value() V {
+	return n.value.value
+}
+
+// Aggregate returns aggregate of all elements in subtree of node.
+func (n *AugmentedNode[K, V, A]) Aggregate() A {
+	return n.value.aggregate
+}
+
+// Index returns position of node in map.
+func (n *AugmentedNode[K, V, A]) Index() int {
+	return n.node().Index()
+}
+
+// Next returns next node.
+//
+// If current node is the last, Next will return nil.
+func (n *AugmentedNode[K, V, A]) Next() *AugmentedNode[K, V, A] {
+	return augmentedNode(n.node().Next())
+}
+
+// Prev returns previous node.
+//
+// If current node is the first, Prev will return nil.
+func (n *AugmentedNode[K, V, A]) Prev() *AugmentedNode[K, V, A] {
+	return augmentedNode(n.node().Prev())
+}
+
+func (n *AugmentedNode[K, V, A]) node() *Node[K, augmentedValue[V, A]] {
+	return (*Node[K, augmentedValue[V, A]])(n)
+}
+
+func augmentedNode[K, V, A any](
+	n *Node[K, augmentedValue[V, A]],
+) *AugmentedNode[K, V, A] {
+	return (*AugmentedNode[K, V, A])(n)
+}
+
+// AugmentedMap represents ordered map that maintains aggregate of
+// each subtree using specified monoid.
+//
+// Node values should be updated only using map methods, otherwise
+// aggregates will be invalid.
+type AugmentedMap[K, V, A any] struct {
+	tree   Map[K, augmentedValue[V, A]]
+	monoid Monoid[K, V, A]
+}
+
+// Get returns value by specified key.
+//
+// If there is no such node, ok will be false.
+func (m *AugmentedMap[K, V, A]) Get(key K) (value V, ok bool) {
+	if it := m.tree.Find(key); it != nil {
+		value = it.value.value
+		ok = true
+	}
+	return
+}
+
+// Set updates value by specified key.
+func (m *AugmentedMap[K, V, A]) Set(key K, value V) {
+	if it := m.tree.Find(key); it != nil {
+		m.SetValue(augmentedNode(it), value)
+		return
+	}
+	m.Insert(key, value)
+}
+
+// Unset removes specified key.
+func (m *AugmentedMap[K, V, A]) Unset(key K) {
+	if it := m.tree.Find(key); it != nil {
+		m.tree.Erase(it)
+	}
+}
+
+// SetValue sets new value to node and updates aggregates.
+func (m *AugmentedMap[K, V, A]) SetValue(n *AugmentedNode[K, V, A], value V) {
+	n.value.value = value
+	for it := n.node(); it != nil; it = it.parent {
+		m.tree.update(it)
+	}
+}
+
+func (m *AugmentedMap[K, V, A]) Insert(key K, value V) *AugmentedNode[K, V, A] {
+	return augmentedNode(m.tree.Insert(key, augmentedValue[V, A]{value: value}))
+}
+
+func (m *AugmentedMap[K, V, A]) Erase(n *AugmentedNode[K, V, A]) {
+	m.tree.Erase(n.node())
+}
+
+// Find finds node with specified key.
+func (m *AugmentedMap[K, V, A]) Find(key K) *AugmentedNode[K, V, A] {
+	return augmentedNode(m.tree.Find(key))
+}
+
+// Front returns first element of map.
+//
+// If there is no nodes, Front will return nil.
+func (m *AugmentedMap[K, V, A]) Front() *AugmentedNode[K, V, A] {
+	return augmentedNode(m.tree.Front())
+}
+
+// Back returns last element of map.
+//
+// If there is no nodes, Back will return nil.
+func (m *AugmentedMap[K, V, A]) Back() *AugmentedNode[K, V, A] {
+	return augmentedNode(m.tree.Back())
+}
+
+// LowerBound returns the smallest node with node.key >= key.
+//
+// If there is no such nodes, LowerBound will return nil.
+func (m *AugmentedMap[K, V, A]) LowerBound(key K) *AugmentedNode[K, V, A] {
+	return augmentedNode(m.tree.LowerBound(key))
+}
+
+// Rank returns amount of nodes with node.key < key.
+func (m *AugmentedMap[K, V, A]) Rank(key K) int {
+	return m.tree.Rank(key)
+}
+
+// At returns node with specified index.
+//
+// If index is out of range, At will return nil.
+func (m *AugmentedMap[K, V, A]) At(i int) *AugmentedNode[K, V, A] {
+	return augmentedNode(m.tree.At(i))
+}
+
+// Len returns amount of elements in map.
+func (m *AugmentedMap[K, V, A]) Len() int {
+	return m.tree.Len()
+}
+
+// Reduce returns aggregate of all elements with lo <= key < hi.
+//
+// If there is no such elements, Reduce will return identity.
+func (m *AugmentedMap[K, V, A]) Reduce(lo, hi K) A {
+	less := m.tree.less
+	it := m.tree.root
+	for it != nil {
+		if less(it.key, lo) {
+			it = it.right
+		} else if !less(it.key, hi) {
+			it = it.left
+		} else {
+			break
+		}
+	}
+	if it == nil {
+		return m.monoid.Identity
+	}
+	// All elements in range are in subtree of it, so we aggregate
+	// suffix of left subtree and prefix of right subtree.
+	left := m.monoid.Identity
+	for n := it.left; n != nil; {
+		if less(n.key, lo) {
+			n = n.right
+		} else {
+			a := m.project(n)
+			if n.right != nil {
+				a = m.monoid.Combine(a, n.right.value.aggregate)
+			}
+			left = m.monoid.Combine(a, left)
+			n = n.left
+		}
+	}
+	right := m.monoid.Identity
+	for n := it.right; n != nil; {
+		if !less(n.key, hi) {
+			n = n.left
+		} else {
+			a := m.project(n)
+			if n.left != nil {
+				a = m.monoid.Combine(n.left.value.aggregate, a)
+			}
+			right = m.monoid.Combine(right, a)
+			n = n.right
+		}
+	}
+	return m.monoid.Combine(m.monoid.Combine(left, m.project(it)), right)
+}
+
+// Clone creates copy of map.
+func (m *AugmentedMap[K, V, A]) Clone() *AugmentedMap[K, V, A] {
+	c := AugmentedMap[K, V, A]{tree: *m.tree.Clone(), monoid: m.monoid}
+	c.tree.update = c.updateNode
+	return &c
+}
+
+// NewAugmentedMap creates new instance of ordered map augmented with
+// specified monoid.
+func NewAugmentedMap[K, V, A any](
+	less func(K, K) bool, monoid Monoid[K, V, A],
+) *AugmentedMap[K, V, A] {
+	m := AugmentedMap[K, V, A]{monoid: monoid}
+	m.tree.less = less
+	m.tree.update = m.updateNode
+	return &m
+}
+
+func (m *AugmentedMap[K, V, A]) project(n *Node[K, augmentedValue[V, A]]) A {
+	return m.monoid.Project(n.key, n.value.value)
+}
+
+func (m *AugmentedMap[K, V, A]) updateNode(n *Node[K, augmentedValue[V, A]]) {
+	a := m.project(n)
+	if n.left != nil {
+		a = m.monoid.Combine(n.left.value.aggregate, a)
+	}
+	if n.right != nil {
+		a = m.monoid.Combine(a, n.right.value.aggregate)
+	}
+	n.value.aggregate = a
+}
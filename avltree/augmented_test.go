@@ -0,0 +1,125 @@
+package avltree
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func newSumMap() *AugmentedMap[int, int, int] {
+	return NewAugmentedMap(intLess, Monoid[int, int, int]{
+		Combine: func(x, y int) int { return x + y },
+		Project: func(key, value int) int { return value },
+	})
+}
+
+func TestAugmentedMapReduce(t *testing.T) {
+	m := newSumMap()
+	rnd := rand.New(rand.NewSource(42))
+	n := 500
+	values := map[int]int{}
+	check := func(c *AugmentedMap[int, int, int]) {
+		for i := 0; i < 200; i++ {
+			lo, hi := rnd.Intn(n+10)-5, rnd.Intn(n+10)-5
+			expected := 0
+			for k, v := range values {
+				if k >= lo && k < hi {
+					expected += v
+				}
+			}
+			if v := c.Reduce(lo, hi); v != expected {
+				t.Fatalf("Expected sum [%d, %d) = %d, got %d", lo, hi, expected, v)
+			}
+		}
+	}
+	for _, k := range rnd.Perm(n) {
+		values[k] = rnd.Intn(1000)
+		m.Set(k, values[k])
+	}
+	check(m)
+	check(m.Clone())
+	for i := 0; i < n/2; i++ {
+		k := rnd.Intn(n)
+		if i%2 == 0 {
+			delete(values, k)
+			m.Unset(k)
+		} else {
+			values[k] = rnd.Intn(1000)
+			m.Set(k, values[k])
+		}
+	}
+	check(m)
+	for it := m.Front(); it != nil; it = it.Next() {
+		if v := it.Value(); v != values[it.Key()] {
+			t.Fatalf("Expected value = %d, got %d", values[it.Key()], v)
+		}
+	}
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	if m.Len() > 0 {
+		if v := m.tree.root.value.aggregate; v != sum {
+			t.Fatalf("Expected sum = %d, got %d", sum, v)
+		}
+	}
+}
+
+func TestAugmentedMapOrder(t *testing.T) {
+	m := NewAugmentedMap(intLess, Monoid[int, struct{}, string]{
+		Combine: func(x, y string) string { return x + y },
+		Project: func(key int, _ struct{}) string {
+			return strconv.Itoa(key%10) + ","
+		},
+	})
+	rnd := rand.New(rand.NewSource(42))
+	n := 100
+	for _, k := range rnd.Perm(n) {
+		m.Insert(k, struct{}{})
+	}
+	for i := 0; i < 100; i++ {
+		lo, hi := rnd.Intn(n), rnd.Intn(n)
+		expected := ""
+		for k := lo; k < hi; k++ {
+			expected += strconv.Itoa(k%10) + ","
+		}
+		if v := m.Reduce(lo, hi); v != expected {
+			t.Fatalf("Expected %q, got %q", expected, v)
+		}
+	}
+	for it := m.Front(); it != nil; {
+		jt := it.Next()
+		if it.Key()%3 == 0 {
+			m.Erase(it)
+		}
+		it = jt
+	}
+	expected := ""
+	for k := 0; k < n; k++ {
+		if k%3 != 0 {
+			expected += strconv.Itoa(k%10) + ","
+		}
+	}
+	if v := m.Reduce(0, n); v != expected {
+		t.Fatalf("Expected %q, got %q", expected, v)
+	}
+	if v := m.Find(1).Prev(); v != nil {
+		t.Fatalf("Expected nil node, got %d", v.Key())
+	}
+	if v := m.At(1); v.Key() != 2 || v.Index() != 1 || m.Rank(2) != 1 {
+		t.Fatalf("Invalid node at %d", 1)
+	}
+}
+
+func BenchmarkAvltreeAugmentedMapReduce(b *testing.B) {
+	m := newSumMap()
+	for i := 0; i < b.N; i++ {
+		m.Insert(i, i)
+	}
+	rnd := rand.New(rand.NewSource(42))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lo := rnd.Intn(b.N)
+		m.Reduce(lo, lo+100)
+	}
+}
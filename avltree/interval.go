@@ -0,0 +1,131 @@
+package avltree
+
+type intervalValue[K, V any] struct {
+	high  K
+	value V
+}
+
+// intervalMax represents maximal high endpoint of intervals.
+type intervalMax[K any] struct {
+	high K
+	ok   bool
+}
+
+// IntervalNode represents element of interval tree.
+type IntervalNode[K, V any] AugmentedNode[K, intervalValue[K, V], intervalMax[K]]
+
+// Low returns low endpoint of interval.
+func (n *IntervalNode[K, V]) Low() K {
+	return n.key
+}
+
+// High returns high endpoint of interval.
+func (n *IntervalNode[K, V]) High() K {
+	return n.value.value.high
+}
+
+// Value returns node value.
+func (n *IntervalNode[K, V]) Value() V {
+	return n.value.value.value
+}
+
+// SetValue sets new value to node.
+func (n *IntervalNode[K, V]) SetValue(value V) {
+	n.value.value.value = value
+}
+
+func (n *IntervalNode[K, V]) node() *AugmentedNode[K, intervalValue[K, V], intervalMax[K]] {
+	return (*AugmentedNode[K, intervalValue[K, V], intervalMax[K]])(n)
+}
+
+// IntervalTree represents collection of closed intervals [low, high].
+//
+// Intervals are ordered by low endpoints and each subtree maintains
+// maximal high endpoint of its intervals, so queries skip subtrees
+// without overlapping intervals and take O((k + 1) log n) time in the
+// worst case, where k is amount of reported intervals. Tree can
+// contain equal intervals.
+type IntervalTree[K, V any] struct {
+	tree *AugmentedMap[K, intervalValue[K, V], intervalMax[K]]
+}
+
+// Insert inserts interval [low, high] with specified value.
+//
+// Low endpoint should not be greater than high endpoint.
+func (t *IntervalTree[K, V]) Insert(low, high K, value V) *IntervalNode[K, V] {
+	if t.tree.tree.less(high, low) {
+		panic("low endpoint is greater than high endpoint")
+	}
+	v := intervalValue[K, V]{high: high, value: value}
+	return (*IntervalNode[K, V])(t.tree.Insert(low, v))
+}
+
+// Erase removes interval from tree.
+func (t *IntervalTree[K, V]) Erase(n *IntervalNode[K, V]) {
+	t.tree.Erase(n.node())
+}
+
+// Len returns amount of intervals in tree.
+func (t *IntervalTree[K, V]) Len() int {
+	return t.tree.Len()
+}
+
+// Overlapping calls fn for each interval that overlaps [low, high]
+// in order of low endpoints.
+//
+// If fn returns false, iteration will be stopped.
+func (t *IntervalTree[K, V]) Overlapping(
+	low, high K, fn func(*IntervalNode[K, V]) bool,
+) {
+	t.overlapping(t.tree.tree.root, low, high, fn)
+}
+
+// Stabbing calls fn for each interval that contains point in order
+// of low endpoints.
+//
+// If fn returns false, iteration will be stopped.
+func (t *IntervalTree[K, V]) Stabbing(point K, fn func(*IntervalNode[K, V]) bool) {
+	t.Overlapping(point, point, fn)
+}
+
+// NewIntervalTree creates new instance of interval tree.
+func NewIntervalTree[K, V any](less func(K, K) bool) *IntervalTree[K, V] {
+	monoid := Monoid[K, intervalValue[K, V], intervalMax[K]]{
+		Combine: func(x, y intervalMax[K]) intervalMax[K] {
+			if !x.ok || (y.ok && less(x.high, y.high)) {
+				return y
+			}
+			return x
+		},
+		Project: func(_ K, v intervalValue[K, V]) intervalMax[K] {
+			return intervalMax[K]{high: v.high, ok: true}
+		},
+	}
+	return &IntervalTree[K, V]{tree: NewAugmentedMap(less, monoid)}
+}
+
+// overlapping returns false if iteration is stopped.
+func (t *IntervalTree[K, V]) overlapping(
+	n *Node[K, augmentedValue[intervalValue[K, V], intervalMax[K]]],
+	low, high K, fn func(*IntervalNode[K, V]) bool,
+) bool {
+	less := t.tree.tree.less
+	for n != nil {
+		// All intervals of subtree end before low.
+		if less(n.value.aggregate.high, low) {
+			return true
+		}
+		if !t.overlapping(n.left, low, high, fn) {
+			return false
+		}
+		// Intervals of right subtree start after high.
+		if less(high, n.key) {
+			return true
+		}
+		if !less(n.value.value.high, low) && !fn((*IntervalNode[K, V])(n)) {
+			return false
+		}
+		n = n.right
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package avltree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type testInterval struct {
+	low, high int
+	node      *IntervalNode[int, int]
+}
+
+func TestIntervalTree(t *testing.T) {
+	tree := NewIntervalTree[int, int](intLess)
+	rnd := rand.New(rand.NewSource(42))
+	n := 1000
+	var intervals []testInterval
+	for i := 0; i < n; i++ {
+		low := rnd.Intn(10000)
+		high := low + rnd.Intn(500)
+		node := tree.Insert(low, high, i)
+		intervals = append(intervals, testInterval{low, high, node})
+		if i%4 == 0 {
+			j := rnd.Intn(len(intervals))
+			tree.Erase(intervals[j].node)
+			intervals = append(intervals[:j], intervals[j+1:]...)
+		}
+	}
+	if v := tree.Len(); v != len(intervals) {
+		t.Fatalf("Expected len = %d, got %d", len(intervals), v)
+	}
+	check := func(low, high int, nodes []*IntervalNode[int, int]) {
+		expected := map[*IntervalNode[int, int]]struct{}{}
+		for _, v := range intervals {
+			if v.low <= high && v.high >= low {
+				expected[v.node] = struct{}{}
+			}
+		}
+		if len(nodes) != len(expected) {
+			t.Fatalf("Expected %d intervals, got %d", len(expected), len(nodes))
+		}
+		for i, node := range nodes {
+			if _, ok := expected[node]; !ok {
+				t.Fatalf("Unexpected interval [%d, %d]", node.Low(), node.High())
+			}
+			if i > 0 && node.Low() < nodes[i-1].Low() {
+				t.Fatal("Intervals are out of order")
+			}
+		}
+	}
+	for i := 0; i < 200; i++ {
+		low := rnd.Intn(11000) - 500
+		high := low + rnd.Intn(300)
+		var nodes []*IntervalNode[int, int]
+		tree.Overlapping(low, high, func(n *IntervalNode[int, int]) bool {
+			nodes = append(nodes, n)
+			return true
+		})
+		check(low, high, nodes)
+		nodes = nil
+		tree.Stabbing(low, func(n *IntervalNode[int, int]) bool {
+			nodes = append(nodes, n)
+			return true
+		})
+		check(low, low, nodes)
+	}
+	count := 0
+	tree.Overlapping(0, 10000, func(n *IntervalNode[int, int]) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Fatalf("Expected %d calls, got %d", 10, count)
+	}
+}
+
+func TestIntervalNodeValue(t *testing.T) {
+	tree := NewIntervalTree[int, string](intLess)
+	n := tree.Insert(1, 5, "a")
+	n.SetValue("b")
+	tree.Stabbing(5, func(n *IntervalNode[int, string]) bool {
+		if v := n.Value(); v != "b" {
+			t.Fatalf("Expected value = %q, got %q", "b", v)
+		}
+		return true
+	})
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Expected panic")
+		}
+	}()
+	tree.Insert(5, 1, "c")
+}
+
+func BenchmarkAvltreeIntervalTreeStabbing(b *testing.B) {
+	tree := NewIntervalTree[int, int](intLess)
+	rnd := rand.New(rand.NewSource(42))
+	for i := 0; i < b.N; i++ {
+		low := rnd.Intn(b.N * 10)
+		tree.Insert(low, low+rnd.Intn(100), i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Stabbing(rnd.Intn(b.N*10), func(*IntervalNode[int, int]) bool {
+			return true
+		})
+	}
+}
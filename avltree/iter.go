@@ -0,0 +1,102 @@
+package avltree
+
+import "iter"
+
+// All returns iterator over all elements of map in ascending order.
+//
+// Map can be modified during iteration. If the last yielded node is
+// removed, iteration continues from the first element with greater
+// key, otherwise it continues from the next node.
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.walk(m.Front(), false, func(n *Node[K, V]) bool {
+			return yield(n.key, n.value)
+		})
+	}
+}
+
+// Backward returns iterator over all elements of map in descending
+// order.
+//
+// Map can be modified during iteration. If the last yielded node is
+// removed, iteration continues from the last element with smaller
+// key, otherwise it continues from the previous node.
+func (m *Map[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.walk(m.Back(), true, func(n *Node[K, V]) bool {
+			return yield(n.key, n.value)
+		})
+	}
+}
+
+// Range returns iterator over elements with lo <= key < hi in
+// ascending order.
+//
+// Map can be modified during iteration in the same way as for All.
+func (m *Map[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.walk(m.LowerBound(lo), false, func(n *Node[K, V]) bool {
+			return m.less(n.key, hi) && yield(n.key, n.value)
+		})
+	}
+}
+
+// RangeFrom returns iterator over elements with key >= lo in
+// ascending order.
+//
+// Map can be modified during iteration in the same way as for All.
+func (m *Map[K, V]) RangeFrom(lo K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.walk(m.LowerBound(lo), false, func(n *Node[K, V]) bool {
+			return yield(n.key, n.value)
+		})
+	}
+}
+
+// Keys returns iterator over all keys of map in ascending order.
+func (m *Map[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.walk(m.Front(), false, func(n *Node[K, V]) bool {
+			return yield(n.key)
+		})
+	}
+}
+
+// Values returns iterator over all values of map in ascending order
+// of keys.
+func (m *Map[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.walk(m.Front(), false, func(n *Node[K, V]) bool {
+			return yield(n.value)
+		})
+	}
+}
+
+// walk calls yield for each node starting from n until yield
+// returns false.
+//
+// If map is modified by yield and yielded node is removed from map,
+// walk finds the following node by key.
+func (m *Map[K, V]) walk(n *Node[K, V], backward bool, yield func(*Node[K, V]) bool) {
+	for n != nil {
+		version := m.version
+		if !yield(n) {
+			return
+		}
+		if m.version != version && getRoot(n) != m.root {
+			if backward {
+				if next := m.LowerBound(n.key); next != nil {
+					n = next.Prev()
+				} else {
+					n = m.Back()
+				}
+			} else {
+				n = m.UpperBound(n.key)
+			}
+		} else if backward {
+			n = n.Prev()
+		} else {
+			n = n.Next()
+		}
+	}
+}
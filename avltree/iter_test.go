@@ -0,0 +1,131 @@
+package avltree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMapIterators(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	n := 100
+	for i := 0; i < n; i++ {
+		m.Insert(i*2, i)
+	}
+	var keys []int
+	for k, v := range m.All() {
+		if v*2 != k {
+			t.Fatalf("Expected value = %d, got %d", k/2, v)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) != n || !slices.IsSorted(keys) {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	if v := slices.Collect(m.Keys()); !slices.Equal(v, keys) {
+		t.Fatalf("Invalid keys: %v", v)
+	}
+	if v := slices.Collect(m.Values()); len(v) != n || v[n-1] != n-1 {
+		t.Fatalf("Invalid values: %v", v)
+	}
+	var backward []int
+	for k := range m.Backward() {
+		backward = append(backward, k)
+	}
+	slices.Reverse(backward)
+	if !slices.Equal(backward, keys) {
+		t.Fatalf("Invalid keys: %v", backward)
+	}
+	var rng []int
+	for k := range m.Range(9, 20) {
+		rng = append(rng, k)
+	}
+	if !slices.Equal(rng, []int{10, 12, 14, 16, 18}) {
+		t.Fatalf("Invalid keys: %v", rng)
+	}
+	rng = nil
+	for k := range m.RangeFrom(191) {
+		rng = append(rng, k)
+	}
+	if !slices.Equal(rng, []int{192, 194, 196, 198}) {
+		t.Fatalf("Invalid keys: %v", rng)
+	}
+	for range m.Range(20, 10) {
+		t.Fatal("Expected empty range")
+	}
+	cnt := 0
+	for range m.All() {
+		cnt++
+		if cnt == 10 {
+			break
+		}
+	}
+	if cnt != 10 {
+		t.Fatalf("Expected %d iterations, got %d", 10, cnt)
+	}
+}
+
+func TestMapIteratorsModification(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	n := 100
+	for i := 0; i < n; i++ {
+		m.Insert(i, i)
+	}
+	// Removal of current and next nodes.
+	var keys []int
+	for k := range m.All() {
+		keys = append(keys, k)
+		m.Unset(k)
+		m.Unset(k + 1)
+	}
+	if len(keys) != n/2 || keys[1] != 2 || m.Len() != 0 {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	for i := 0; i < n; i++ {
+		m.Insert(i, i)
+	}
+	// Insertion of nodes after current node.
+	keys = nil
+	for k := range m.Range(0, n+10) {
+		keys = append(keys, k)
+		if k >= n-1 && k < n+5 {
+			m.Insert(k+1, k+1)
+		}
+	}
+	if len(keys) != n+6 || !slices.IsSorted(keys) {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	keys = nil
+	for k := range m.Backward() {
+		keys = append(keys, k)
+		m.Unset(k)
+		m.Unset(k - 1)
+	}
+	if len(keys) != (n+6)/2 || m.Len() != 0 {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	for i := 0; i < n; i++ {
+		m.Insert(i, i)
+	}
+	// Clearing of map.
+	keys = nil
+	for k := range m.All() {
+		keys = append(keys, k)
+		m.Difference(m)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+}
+
+func BenchmarkAvltreeSimpleIntMapAll(b *testing.B) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < b.N; i++ {
+		m.Insert(i, i)
+	}
+	b.ResetTimer()
+	for k, v := range m.All() {
+		if k != v {
+			b.Fatalf("Expected value = %d, got %d", k, v)
+		}
+	}
+}
@@ -5,6 +5,7 @@ type Node[K, V any] struct {
 	key    K
 	value  V
 	height int8
+	size   int
 	left   *Node[K, V]
 	right  *Node[K, V]
 	parent *Node[K, V]
@@ -25,6 +26,19 @@ func (n *Node[K, V]) SetValue(value V) {
 	n.value = value
 }
 
+// Index returns position of node in map.
+//
+// Index of the first node is 0.
+func (n *Node[K, V]) Index() int {
+	i := n.left.getSize()
+	for ; n.parent != nil; n = n.parent {
+		if n.parent.right == n {
+			i += n.parent.left.getSize() + 1
+		}
+	}
+	return i
+}
+
 // Next returns next node.
 //
 // If current node is the last, Next will return nil.
@@ -66,10 +80,19 @@ func (n *Node[K, V]) Prev() *Node[K, V] {
 // Map is not copyable, but Clone method creates a valid copy of map.
 // Map is thread-safe in read-only mode. For parallel read-write use
 // sync.RWMutex to avoid any race conditions.
+//
+// Union, Intersection, Difference and SymmetricDifference assume that
+// keys of both maps are unique. If map contains several nodes with
+// equal keys added by Insert, only one of them is matched with node
+// of other map, and the rest are treated as missing in other map.
 type Map[K, V any] struct {
 	root *Node[K, V]
 	less func(K, K) bool
 	len  int
+	// update is called for each node after its subtree has changed.
+	update func(*Node[K, V])
+	// version is changed on each modification of map structure.
+	version uint64
 }
 
 // Get returns value by specified key.
@@ -85,11 +108,71 @@ func (m *Map[K, V]) Get(key K) (value V, ok bool) {
 
 // Set updates value by specified key.
 func (m *Map[K, V]) Set(key K, value V) {
-	if it := m.Find(key); it != nil {
-		it.value = value
+	n, parent, left := m.lookup(key)
+	if n != nil {
+		n.value = value
 		return
 	}
-	m.Insert(key, value)
+	m.attach(&Node[K, V]{key: key, value: value}, parent, left)
+}
+
+// Upsert sets value returned by fn and returns it. Function fn
+// receives current value and flag that key exists.
+//
+// If there are several nodes with specified key, Upsert will update
+// the first of them.
+func (m *Map[K, V]) Upsert(key K, fn func(old V, exists bool) V) V {
+	n, parent, left := m.lookup(key)
+	if n != nil {
+		n.value = fn(n.value, true)
+		return n.value
+	}
+	var empty V
+	n = &Node[K, V]{key: key, value: fn(empty, false)}
+	m.attach(n, parent, left)
+	return n.value
+}
+
+// GetOrSet returns current value and true if key exists, otherwise
+// sets specified value and returns it with false.
+func (m *Map[K, V]) GetOrSet(key K, value V) (V, bool) {
+	n, parent, left := m.lookup(key)
+	if n != nil {
+		return n.value, true
+	}
+	m.attach(&Node[K, V]{key: key, value: value}, parent, left)
+	return value, false
+}
+
+// Swap sets value and returns previous value and flag that key
+// existed.
+func (m *Map[K, V]) Swap(key K, value V) (old V, ok bool) {
+	n, parent, left := m.lookup(key)
+	if n != nil {
+		old, n.value = n.value, value
+		return old, true
+	}
+	m.attach(&Node[K, V]{key: key, value: value}, parent, left)
+	return
+}
+
+// Compute sets value returned by fn, or removes node if fn returns
+// false. Function fn receives current value and flag that key exists.
+func (m *Map[K, V]) Compute(key K, fn func(old V, exists bool) (V, bool)) {
+	n, parent, left := m.lookup(key)
+	if n != nil {
+		value, keep := fn(n.value, true)
+		if keep {
+			n.value = value
+		} else {
+			m.Erase(n)
+		}
+		return
+	}
+	var empty V
+	if value, keep := fn(empty, false); keep {
+		m.attach(&Node[K, V]{key: key, value: value}, parent, left)
+	}
 }
 
 // Unset removes specified key.
@@ -99,40 +182,72 @@ func (m *Map[K, V]) Unset(key K) {
 	}
 }
 
+// Insert inserts new node even if map already contains specified key.
+//
+// Nodes with equal keys are kept in order of insertion.
 func (m *Map[K, V]) Insert(key K, value V) *Node[K, V] {
-	n := Node[K, V]{key: key, value: value, height: 1}
-	if m.root == nil {
-		m.root = &n
-		m.len = 1
-		return &n
+	var parent *Node[K, V]
+	left := false
+	for it := m.root; it != nil; {
+		parent = it
+		left = m.less(key, it.key)
+		if left {
+			it = it.left
+		} else {
+			it = it.right
+		}
 	}
-	p := m.root
-	for {
-		if m.less(key, p.key) {
-			if p.left == nil {
-				p.left = &n
-				n.parent = p
-				break
-			}
-			p = p.left
+	n := Node[K, V]{key: key, value: value}
+	m.attach(&n, parent, left)
+	return &n
+}
+
+// lookup returns the first node with specified key. If there is no
+// such node, lookup returns parent and side of new node.
+//
+// Upsert, GetOrSet, Swap and Compute use lookup and attach, so they
+// find key only once.
+func (m *Map[K, V]) lookup(key K) (n, parent *Node[K, V], left bool) {
+	for it := m.root; it != nil; {
+		parent = it
+		if m.less(it.key, key) {
+			left = false
+			it = it.right
 		} else {
-			if p.right == nil {
-				p.right = &n
-				n.parent = p
-				break
-			}
-			p = p.right
+			n = it
+			left = true
+			it = it.left
 		}
 	}
+	if n != nil && !m.less(key, n.key) {
+		return n, nil, false
+	}
+	return nil, parent, left
+}
+
+// attach links new node as child of parent and rebalances tree.
+func (m *Map[K, V]) attach(n, parent *Node[K, V], left bool) {
+	m.recalc(n)
+	m.version++
 	m.len++
-	m.rebalance(p)
-	return &n
+	if parent == nil {
+		m.root = n
+		return
+	}
+	n.parent = parent
+	if left {
+		parent.left = n
+	} else {
+		parent.right = n
+	}
+	m.rebalance(parent)
 }
 
 func (m *Map[K, V]) Erase(n *Node[K, V]) {
 	if r := getRoot(n); r != m.root {
 		panic("attempt to erase node from wrong map")
 	}
+	m.version++
 	if n.left == nil && n.right == nil {
 		if n.parent == nil {
 			m.root = nil
@@ -243,7 +358,8 @@ func (m *Map[K, V]) Erase(n *Node[K, V]) {
 
 // Find finds node with specified key.
 //
-// It is safe to access nodes concurently.
+// If there are several nodes with specified key, Find will return
+// the first of them. It is safe to access nodes concurently.
 func (m *Map[K, V]) Find(key K) *Node[K, V] {
 	n := m.LowerBound(key)
 	if n == nil || m.less(key, n.key) {
@@ -295,16 +411,171 @@ func (m *Map[K, V]) LowerBound(key K) (n *Node[K, V]) {
 	return
 }
 
+// UpperBound returns the smallest node with node.key > key.
+//
+// If there is no such nodes, UpperBound will return nil.
+func (m *Map[K, V]) UpperBound(key K) (n *Node[K, V]) {
+	for it := m.root; it != nil; {
+		if m.less(key, it.key) {
+			n = it
+			it = it.left
+		} else {
+			it = it.right
+		}
+	}
+	return
+}
+
+// EqualRange returns the first and the last nodes with specified key.
+//
+// If there is no such nodes, EqualRange will return nils.
+func (m *Map[K, V]) EqualRange(key K) (first, last *Node[K, V]) {
+	first = m.Find(key)
+	if first == nil {
+		return nil, nil
+	}
+	if last = m.UpperBound(key); last != nil {
+		last = last.Prev()
+	} else {
+		last = m.Back()
+	}
+	return first, last
+}
+
+// Count returns amount of nodes with specified key.
+func (m *Map[K, V]) Count(key K) int {
+	c := 0
+	for it := m.root; it != nil; {
+		if m.less(key, it.key) {
+			it = it.left
+		} else {
+			c += it.left.getSize() + 1
+			it = it.right
+		}
+	}
+	return c - m.Rank(key)
+}
+
+// EraseAll removes all nodes with specified key and returns amount
+// of removed nodes.
+func (m *Map[K, V]) EraseAll(key K) int {
+	c := 0
+	for it := m.Find(key); it != nil && !m.less(key, it.key); c++ {
+		next := it.Next()
+		m.Erase(it)
+		it = next
+	}
+	return c
+}
+
+// DeleteRange removes all nodes with lo <= node.key < hi and returns
+// amount of removed nodes.
+//
+// DeleteRange takes O(log n) time regardless of amount of removed nodes.
+func (m *Map[K, V]) DeleteRange(lo, hi K) int {
+	if m.root == nil || !m.less(lo, hi) {
+		return 0
+	}
+	l, r := m.split(m.root, lo)
+	mid, r := m.split(r, hi)
+	m.root = m.join2(l, r)
+	c := mid.getSize()
+	m.len -= c
+	m.version++
+	return c
+}
+
+// Rank returns amount of nodes with node.key < key.
+//
+// Rank equals to index of LowerBound node, or Len if there is no such node.
+func (m *Map[K, V]) Rank(key K) int {
+	r := 0
+	for it := m.root; it != nil; {
+		if m.less(it.key, key) {
+			r += it.left.getSize() + 1
+			it = it.right
+		} else {
+			it = it.left
+		}
+	}
+	return r
+}
+
+// At returns node with specified index.
+//
+// If index is out of range, At will return nil.
+func (m *Map[K, V]) At(i int) *Node[K, V] {
+	if i < 0 || i >= m.len {
+		return nil
+	}
+	it := m.root
+	for {
+		s := it.left.getSize()
+		if i < s {
+			it = it.left
+		} else if i > s {
+			i -= s + 1
+			it = it.right
+		} else {
+			return it
+		}
+	}
+}
+
 // Len returns amount of elements in map.
 func (m *Map[K, V]) Len() int {
 	return m.len
 }
 
+// Split moves nodes with node.key < key to left map and other
+// nodes to right map.
+//
+// After Split current map will be empty. Split takes O(log n) time.
+func (m *Map[K, V]) Split(key K) (left, right *Map[K, V]) {
+	l, r := m.split(m.root, key)
+	left = &Map[K, V]{root: l, less: m.less, len: l.getSize(), update: m.update}
+	right = &Map[K, V]{root: r, less: m.less, len: r.getSize(), update: m.update}
+	m.root = nil
+	m.len = 0
+	m.version++
+	return left, right
+}
+
+// Join moves all nodes from other map to the end of current map.
+//
+// Keys of other map should not be less than keys of current map,
+// otherwise Join will panic. After Join other map will be empty.
+// Join takes O(log n) time.
+func (m *Map[K, V]) Join(other *Map[K, V]) {
+	if m == other {
+		panic("attempt to join map with itself")
+	}
+	if other.root == nil {
+		return
+	}
+	m.version++
+	other.version++
+	if m.root == nil {
+		m.root, m.len = other.root, other.len
+		other.root, other.len = nil, 0
+		return
+	}
+	k := other.Front()
+	if m.less(k.key, m.Back().key) {
+		panic("attempt to join overlapping maps")
+	}
+	other.Erase(k)
+	m.root = m.join(m.root, k, other.root)
+	m.len += other.len + 1
+	other.root, other.len = nil, 0
+}
+
 // Clone creates copy of map.
 func (m *Map[K, V]) Clone() *Map[K, V] {
 	c := Map[K, V]{
-		less: m.less,
-		len:  m.len,
+		less:   m.less,
+		len:    m.len,
+		update: m.update,
 	}
 	if m.root != nil {
 		c.root = m.root.clone()
@@ -318,26 +589,95 @@ func NewMap[K, V any](less func(K, K) bool) *Map[K, V] {
 }
 
 func (m *Map[K, V]) rebalance(n *Node[K, V]) {
+	m.root = m.rebalanceUp(n)
+}
+
+// rebalanceUp rebalances all nodes from n to root and returns root.
+func (m *Map[K, V]) rebalanceUp(n *Node[K, V]) *Node[K, V] {
 	for {
 		if b := n.balance(); b > 1 {
 			if n.left.balance() < 0 {
-				n.left = n.left.leftRotate()
+				n.left = m.leftRotate(n.left)
 			}
-			n = n.rightRotate()
+			n = m.rightRotate(n)
 		} else if b < -1 {
 			if n.right.balance() > 0 {
-				n.right = n.right.rightRotate()
+				n.right = m.rightRotate(n.right)
 			}
-			n = n.leftRotate()
+			n = m.leftRotate(n)
 		} else {
-			n.recalcHeight()
+			m.recalc(n)
 		}
 		if n.parent == nil {
-			break
+			return n
 		}
 		n = n.parent
 	}
-	m.root = n
+}
+
+// join links trees l and r using node k and returns root of resulting
+// tree. Keys of l should not be greater than k.key and keys of r should
+// not be less than k.key. Roots of l and r should have no parents.
+func (m *Map[K, V]) join(l, k, r *Node[K, V]) *Node[K, V] {
+	lh, rh := l.getHeight(), r.getHeight()
+	if lh > rh+1 {
+		p := l
+		for p.right.getHeight() > rh+1 {
+			p = p.right
+		}
+		m.link(k, p.right, r)
+		p.right = k
+		k.parent = p
+		return m.rebalanceUp(p)
+	}
+	if rh > lh+1 {
+		p := r
+		for p.left.getHeight() > lh+1 {
+			p = p.left
+		}
+		m.link(k, l, p.left)
+		p.left = k
+		k.parent = p
+		return m.rebalanceUp(p)
+	}
+	m.link(k, l, r)
+	k.parent = nil
+	return k
+}
+
+// link makes l and r children of node k.
+func (m *Map[K, V]) link(k, l, r *Node[K, V]) {
+	k.left = l
+	k.right = r
+	if l != nil {
+		l.parent = k
+	}
+	if r != nil {
+		r.parent = k
+	}
+	m.recalc(k)
+}
+
+// split splits tree n into trees with node.key < key and with
+// node.key >= key.
+func (m *Map[K, V]) split(n *Node[K, V], key K) (*Node[K, V], *Node[K, V]) {
+	if n == nil {
+		return nil, nil
+	}
+	l, r := n.left.detach(), n.right.detach()
+	if m.less(n.key, key) {
+		rl, rr := m.split(r, key)
+		return m.join(l, n, rl), rr
+	}
+	ll, lr := m.split(l, key)
+	return ll, m.join(lr, n, r)
+}
+
+func (m *Map[K, V]) recalc(n *Node[K, V]) {
+	n.recalc()
+	if m.update != nil {
+		m.update(n)
+	}
 }
 
 func (n *Node[K, V]) clone() *Node[K, V] {
@@ -345,6 +685,7 @@ func (n *Node[K, V]) clone() *Node[K, V] {
 		key:    n.key,
 		value:  n.value,
 		height: n.height,
+		size:   n.size,
 	}
 	if n.left != nil {
 		c.left = n.left.clone()
@@ -368,7 +709,30 @@ func (n *Node[K, V]) balance() int8 {
 	return b
 }
 
-func (n *Node[K, V]) recalcHeight() {
+func (n *Node[K, V]) getHeight() int8 {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+// detach removes link to parent from node.
+func (n *Node[K, V]) detach() *Node[K, V] {
+	if n != nil {
+		n.parent = nil
+	}
+	return n
+}
+
+func (n *Node[K, V]) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *Node[K, V]) recalc() {
+	n.size = n.left.getSize() + n.right.getSize() + 1
 	if n.left != nil && n.right != nil {
 		if n.left.height >= n.right.height {
 			n.height = n.left.height + 1
@@ -391,7 +755,7 @@ func (n *Node[K, V]) recalcHeight() {
 //	/      \
 //
 // y        y
-func (n *Node[K, V]) leftRotate() *Node[K, V] {
+func (m *Map[K, V]) leftRotate(n *Node[K, V]) *Node[K, V] {
 	x := n.right
 	y := x.left
 	x.left = n
@@ -408,8 +772,8 @@ func (n *Node[K, V]) leftRotate() *Node[K, V] {
 	if y != nil {
 		y.parent = n
 	}
-	n.recalcHeight()
-	x.recalcHeight()
+	m.recalc(n)
+	m.recalc(x)
 	return x
 }
 
@@ -420,7 +784,7 @@ func (n *Node[K, V]) leftRotate() *Node[K, V] {
 //
 //	\      /
 //	 y    y
-func (n *Node[K, V]) rightRotate() *Node[K, V] {
+func (m *Map[K, V]) rightRotate(n *Node[K, V]) *Node[K, V] {
 	x := n.left
 	y := x.right
 	if n.parent != nil {
@@ -437,8 +801,8 @@ func (n *Node[K, V]) rightRotate() *Node[K, V] {
 	if y != nil {
 		y.parent = n
 	}
-	n.recalcHeight()
-	x.recalcHeight()
+	m.recalc(n)
+	m.recalc(x)
 	return x
 }
 
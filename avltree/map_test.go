@@ -2,6 +2,7 @@ package avltree
 
 import (
 	"math/rand"
+	"slices"
 	"sync"
 	"testing"
 )
@@ -124,6 +125,9 @@ func testCheckBalance(tb testing.TB, n *Node[int, int]) {
 	if n == nil {
 		return
 	}
+	if n.size != n.left.getSize()+n.right.getSize()+1 {
+		tb.Fatal("Invalid subtree size")
+	}
 	h := n.height
 	var lh, rh int8 = 0, 0
 	if n.left != nil {
@@ -149,6 +153,43 @@ func testCheckBalance(tb testing.TB, n *Node[int, int]) {
 	}
 }
 
+// testCheckTree checks links, sizes, heights and order of all nodes.
+func testCheckTree(tb testing.TB, m *Map[int, int]) {
+	var check func(n *Node[int, int])
+	check = func(n *Node[int, int]) {
+		if n == nil {
+			return
+		}
+		if n.left != nil {
+			if n.left.parent != n || intLess(n.key, n.left.key) {
+				tb.Fatal("Invalid left child")
+			}
+			check(n.left)
+		}
+		if n.right != nil {
+			if n.right.parent != n || intLess(n.right.key, n.key) {
+				tb.Fatal("Invalid right child")
+			}
+			check(n.right)
+		}
+		testCheckBalance(tb, n)
+	}
+	if m.root != nil && m.root.parent != nil {
+		tb.Fatal("Root should not have parent")
+	}
+	check(m.root)
+	if v := m.root.getSize(); v != m.Len() {
+		tb.Fatalf("Expected len = %d, got %d", v, m.Len())
+	}
+	prev := 0
+	for it := m.Front(); it != nil; it = it.Next() {
+		if it != m.Front() && intLess(it.key, prev) {
+			tb.Fatalf("Key out of order: %d < %d", it.key, prev)
+		}
+		prev = it.key
+	}
+}
+
 func TestRandomIntMap(t *testing.T) {
 	m := NewMap[int, int](intLess)
 	rnd := rand.New(rand.NewSource(42))
@@ -176,6 +217,282 @@ func TestRandomIntMap(t *testing.T) {
 	}
 }
 
+func TestOrderStatistics(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	rnd := rand.New(rand.NewSource(42))
+	n := 1000
+	p := rnd.Perm(n)
+	for i := 0; i < n; i++ {
+		m.Insert(p[i]*2, i)
+	}
+	check := func(c *Map[int, int]) {
+		i := 0
+		for it := c.Front(); it != nil; it = it.Next() {
+			if v := it.Index(); v != i {
+				t.Fatalf("Expected index = %d, got %d", i, v)
+			}
+			if v := c.At(i); v != it {
+				t.Fatalf("Invalid node at %d", i)
+			}
+			if v := c.Rank(it.Key()); v != i {
+				t.Fatalf("Expected rank = %d, got %d", i, v)
+			}
+			if v := c.Rank(it.Key() + 1); v != i+1 {
+				t.Fatalf("Expected rank = %d, got %d", i+1, v)
+			}
+			i++
+		}
+		if v := c.Rank(-1); v != 0 {
+			t.Fatalf("Expected rank = %d, got %d", 0, v)
+		}
+		if c.At(-1) != nil || c.At(c.Len()) != nil {
+			t.Fatal("Expected nil node")
+		}
+	}
+	check(m)
+	check(m.Clone())
+	for i := 0; i < n/2; i++ {
+		m.Erase(m.At(rnd.Intn(m.Len())))
+	}
+	check(m)
+	if v := m.Len(); v != n-n/2 {
+		t.Fatalf("Expected len = %d, got %d", n-n/2, v)
+	}
+}
+
+func TestSplitJoin(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	for _, n := range []int{0, 1, 2, 10, 100, 1000} {
+		m := NewMap[int, int](intLess)
+		for _, k := range rnd.Perm(n) {
+			m.Insert(k, k)
+		}
+		for i := 0; i < 20; i++ {
+			key := rnd.Intn(n+2) - 1
+			node := m.Find(key)
+			left, right := m.Split(key)
+			if v := m.Len(); v != 0 {
+				t.Fatalf("Expected len = %d, got %d", 0, v)
+			}
+			testCheckTree(t, left)
+			testCheckTree(t, right)
+			l := key
+			if l < 0 {
+				l = 0
+			} else if l > n {
+				l = n
+			}
+			if v := left.Len(); v != l {
+				t.Fatalf("Expected len = %d, got %d", l, v)
+			}
+			if v := right.Len(); v != n-l {
+				t.Fatalf("Expected len = %d, got %d", n-l, v)
+			}
+			if node != nil && right.Front() != node {
+				t.Fatalf("Expected node with key = %d", key)
+			}
+			// Split the right part to get unbalanced trees for join.
+			mid, right := right.Split(key + rnd.Intn(n+1))
+			left.Join(mid)
+			testCheckTree(t, left)
+			left.Join(right)
+			testCheckTree(t, left)
+			if v := left.Len(); v != n {
+				t.Fatalf("Expected len = %d, got %d", n, v)
+			}
+			if mid.Len() != 0 || right.Len() != 0 {
+				t.Fatal("Expected empty map")
+			}
+			if node != nil && left.Find(key) != node {
+				t.Fatalf("Expected node with key = %d", key)
+			}
+			m = left
+		}
+	}
+}
+
+func TestInvalidJoin(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Expected panic")
+		}
+	}()
+	m1 := NewMap[int, int](intLess)
+	m2 := NewMap[int, int](intLess)
+	m1.Insert(2, 2)
+	m2.Insert(1, 1)
+	m1.Join(m2)
+}
+
+func TestMultiMap(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	rnd := rand.New(rand.NewSource(42))
+	keys := 10
+	values := make([][]int, keys)
+	for i := 0; i < 1000; i++ {
+		k := rnd.Intn(keys)
+		m.Insert(k, i)
+		values[k] = append(values[k], i)
+		if i%3 == 0 {
+			// Erase random duplicate to shuffle tree structure.
+			k := rnd.Intn(keys)
+			if len(values[k]) > 0 {
+				j := rnd.Intn(len(values[k]))
+				first, _ := m.EqualRange(k)
+				it := m.At(first.Index() + j)
+				if v := it.Value(); v != values[k][j] {
+					t.Fatalf("Expected value = %d, got %d", values[k][j], v)
+				}
+				m.Erase(it)
+				values[k] = append(values[k][:j], values[k][j+1:]...)
+			}
+		}
+	}
+	testCheckTree(t, m)
+	for k := 0; k < keys; k++ {
+		if v := m.Count(k); v != len(values[k]) {
+			t.Fatalf("Expected count = %d, got %d", len(values[k]), v)
+		}
+		first, last := m.EqualRange(k)
+		if v := m.Find(k); v != first {
+			t.Fatal("Find should return the first node")
+		}
+		if v := m.UpperBound(k); v != last.Next() {
+			t.Fatal("UpperBound should return node after the last")
+		}
+		// Nodes with equal keys should be in order of insertion.
+		it := first
+		for _, v := range values[k] {
+			if it.Key() != k || it.Value() != v {
+				t.Fatalf("Expected value = %d, got %d", v, it.Value())
+			}
+			if it == last {
+				break
+			}
+			it = it.Next()
+		}
+		if it != last {
+			t.Fatal("Expected last node")
+		}
+	}
+	if first, last := m.EqualRange(keys); first != nil || last != nil {
+		t.Fatal("Expected nil nodes")
+	}
+	if v := m.Count(keys); v != 0 {
+		t.Fatalf("Expected count = %d, got %d", 0, v)
+	}
+	if v := m.EraseAll(3); v != len(values[3]) {
+		t.Fatalf("Expected count = %d, got %d", len(values[3]), v)
+	}
+	if v := m.Count(3); v != 0 {
+		t.Fatalf("Expected count = %d, got %d", 0, v)
+	}
+	if v := m.EraseAll(3); v != 0 {
+		t.Fatalf("Expected count = %d, got %d", 0, v)
+	}
+	testCheckTree(t, m)
+}
+
+func TestUpsertCompute(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < 100; i++ {
+		for j := 0; j <= i; j++ {
+			v := m.Upsert(j, func(old int, exists bool) int {
+				if exists != (j < i) {
+					t.Fatalf("Invalid existence of key %d", j)
+				}
+				return old + 1
+			})
+			if v != i-j+1 {
+				t.Fatalf("Expected value = %d, got %d", i-j+1, v)
+			}
+		}
+	}
+	if v, ok := m.GetOrSet(0, -1); !ok || v != 100 {
+		t.Fatalf("Expected value = %d, got %d", 100, v)
+	}
+	if v, ok := m.GetOrSet(100, -1); ok || v != -1 {
+		t.Fatalf("Expected value = %d, got %d", -1, v)
+	}
+	if v, ok := m.Swap(100, -2); !ok || v != -1 {
+		t.Fatalf("Expected value = %d, got %d", -1, v)
+	}
+	if v, ok := m.Swap(101, -3); ok || v != 0 {
+		t.Fatalf("Expected value = %d, got %d", 0, v)
+	}
+	if v, ok := m.Get(100); !ok || v != -2 {
+		t.Fatalf("Expected value = %d, got %d", -2, v)
+	}
+	rnd := rand.New(rand.NewSource(42))
+	expected := map[int]int{}
+	for k, v := range m.All() {
+		expected[k] = v
+	}
+	for i := 0; i < 10000; i++ {
+		k := rnd.Intn(200)
+		m.Compute(k, func(old int, exists bool) (int, bool) {
+			if v, ok := expected[k]; ok != exists || v != old {
+				t.Fatalf("Expected value = %d, got %d", v, old)
+			}
+			if old%3 == 0 {
+				delete(expected, k)
+				return 0, false
+			}
+			expected[k] = old + i
+			return old + i, true
+		})
+		if v := m.Len(); v != len(expected) {
+			t.Fatalf("Expected len = %d, got %d", len(expected), v)
+		}
+	}
+	for k, v := range m.All() {
+		if expected[k] != v {
+			t.Fatalf("Expected value = %d, got %d", expected[k], v)
+		}
+	}
+	testCheckTree(t, m)
+}
+
+func TestDeleteRange(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	for _, n := range []int{0, 10, 100, 3000} {
+		m := NewMap[int, int](intLess)
+		var keys []int
+		for _, k := range rnd.Perm(n) {
+			m.Set(k*2, k)
+		}
+		for k := 0; k < n; k++ {
+			keys = append(keys, k*2)
+		}
+		for i := 0; i < 100 && len(keys) > 0; i++ {
+			lo := rnd.Intn(2*n+2) - 1
+			hi := lo + rnd.Intn(2*n/(i+1)+2)
+			if i%10 == 0 {
+				hi = lo - 1
+			}
+			l, _ := slices.BinarySearch(keys, lo)
+			r, _ := slices.BinarySearch(keys, hi)
+			r = max(l, r)
+			if v := m.DeleteRange(lo, hi); v != r-l {
+				t.Fatalf("Expected %d removed items, got %d", r-l, v)
+			}
+			testCheckTree(t, m)
+			keys = slices.Delete(keys, l, r)
+			if v := m.Len(); v != len(keys) {
+				t.Fatalf("Expected len = %d, got %d", len(keys), v)
+			}
+			if v := slices.Collect(m.Keys()); !slices.Equal(v, keys) {
+				t.Fatalf("Invalid keys: %v", v)
+			}
+			for _, k := range keys {
+				if v, ok := m.Get(k); !ok || v != k/2 {
+					t.Fatalf("Expected value = %d, got %d", k/2, v)
+				}
+			}
+		}
+	}
+}
+
 func TestInvalidErase(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -260,6 +577,35 @@ func BenchmarkAvltreeSimpleIntMapSeqErase(b *testing.B) {
 	}
 }
 
+func BenchmarkAvltreeSimpleIntMapAt(b *testing.B) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < b.N; i++ {
+		m.Insert(i, i)
+	}
+	rnd := rand.New(rand.NewSource(42))
+	b.ResetTimer()
+	p := rnd.Perm(b.N)
+	for i := 0; i < b.N; i++ {
+		if it := m.At(p[i]); it == nil || it.Key() != p[i] {
+			b.Fatalf("Invalid node at %d", p[i])
+		}
+	}
+}
+
+func BenchmarkAvltreeSimpleIntMapSplitJoin(b *testing.B) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < 100000; i++ {
+		m.Insert(i, i)
+	}
+	rnd := rand.New(rand.NewSource(42))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		left, right := m.Split(rnd.Intn(100000))
+		left.Join(right)
+		m = left
+	}
+}
+
 func BenchmarkAvltreeSimpleIntMapInsert(b *testing.B) {
 	rnd := rand.New(rand.NewSource(42))
 	b.ResetTimer()
@@ -0,0 +1,82 @@
+package avltree
+
+import "github.com/udovin/algo/ordered"
+
+// AsOrdered returns adapter of map to common ordered map interface.
+//
+// Adapter uses the first node with specified key, so map should not
+// contain equal keys.
+func AsOrdered[K, V any](m *Map[K, V]) ordered.Map[K, V] {
+	return orderedMap[K, V]{m}
+}
+
+type orderedMap[K, V any] struct {
+	*Map[K, V]
+}
+
+func (m orderedMap[K, V]) Delete(key K) {
+	m.Unset(key)
+}
+
+func (m orderedMap[K, V]) Iter() ordered.Iter[K, V] {
+	return &orderedIter[K, V]{m: m.Map}
+}
+
+type orderedIter[K, V any] struct {
+	m *Map[K, V]
+	n *Node[K, V]
+}
+
+func (it *orderedIter[K, V]) Next() bool {
+	if it.n == nil {
+		it.n = it.m.Front()
+	} else {
+		it.n = it.n.Next()
+	}
+	return it.n != nil
+}
+
+func (it *orderedIter[K, V]) Prev() bool {
+	if it.n == nil {
+		it.n = it.m.Back()
+	} else {
+		it.n = it.n.Prev()
+	}
+	return it.n != nil
+}
+
+func (it *orderedIter[K, V]) First() bool {
+	it.n = it.m.Front()
+	return it.n != nil
+}
+
+func (it *orderedIter[K, V]) Last() bool {
+	it.n = it.m.Back()
+	return it.n != nil
+}
+
+func (it *orderedIter[K, V]) Seek(key K) bool {
+	it.n = it.m.LowerBound(key)
+	return it.n != nil
+}
+
+func (it *orderedIter[K, V]) SeekPrev(key K) bool {
+	if it.n = it.m.UpperBound(key); it.n != nil {
+		it.n = it.n.Prev()
+	} else {
+		it.n = it.m.Back()
+	}
+	return it.n != nil
+}
+
+func (it *orderedIter[K, V]) Key() K {
+	return it.n.key
+}
+
+func (it *orderedIter[K, V]) Value() V {
+	return it.n.value
+}
+
+func (it *orderedIter[K, V]) SetValue(value V) {
+	it.n.value = value
+}
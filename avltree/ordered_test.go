@@ -0,0 +1,14 @@
+package avltree
+
+import (
+	"testing"
+
+	"github.com/udovin/algo/ordered"
+	"github.com/udovin/algo/ordered/orderedtest"
+)
+
+func TestOrderedMap(t *testing.T) {
+	orderedtest.TestMap(t, func() ordered.Map[int, int] {
+		return AsOrdered(NewMap[int, int](intLess))
+	})
+}
@@ -0,0 +1,338 @@
+package avltree
+
+type persistentNode[K, V any] struct {
+	key    K
+	value  V
+	height int8
+	left   *persistentNode[K, V]
+	right  *persistentNode[K, V]
+}
+
+// PersistentMap represents immutable ordered map.
+//
+// Every modification returns new version of map that shares
+// unchanged nodes with previous version, so modifications take
+// O(log n) time and old versions remain valid. PersistentMap is
+// thread-safe, because its nodes are never modified.
+type PersistentMap[K, V any] struct {
+	root *persistentNode[K, V]
+	less func(K, K) bool
+	len  int
+}
+
+// Get returns value by specified key.
+//
+// If there is no such key, ok will be false.
+func (m *PersistentMap[K, V]) Get(key K) (value V, ok bool) {
+	for it := m.root; it != nil; {
+		if m.less(key, it.key) {
+			it = it.left
+		} else if m.less(it.key, key) {
+			it = it.right
+		} else {
+			return it.value, true
+		}
+	}
+	return
+}
+
+// Set returns new version of map with updated value by specified key.
+func (m *PersistentMap[K, V]) Set(key K, value V) *PersistentMap[K, V] {
+	root, added := m.set(m.root, key, value)
+	c := PersistentMap[K, V]{root: root, less: m.less, len: m.len}
+	if added {
+		c.len++
+	}
+	return &c
+}
+
+// Unset returns new version of map without specified key.
+//
+// If there is no such key, Unset will return current version.
+func (m *PersistentMap[K, V]) Unset(key K) *PersistentMap[K, V] {
+	root, removed := m.unset(m.root, key)
+	if !removed {
+		return m
+	}
+	return &PersistentMap[K, V]{root: root, less: m.less, len: m.len - 1}
+}
+
+// Len returns amount of elements in map.
+func (m *PersistentMap[K, V]) Len() int {
+	return m.len
+}
+
+// Cursor returns new cursor over current version of map.
+//
+// Cursor is not positioned, so Next will move it to the first element
+// and Prev will move it to the last element.
+func (m *PersistentMap[K, V]) Cursor() *PersistentCursor[K, V] {
+	return &PersistentCursor[K, V]{m: m}
+}
+
+// NewPersistentMap creates new empty instance of persistent ordered map.
+func NewPersistentMap[K, V any](less func(K, K) bool) *PersistentMap[K, V] {
+	return &PersistentMap[K, V]{less: less}
+}
+
+func (m *PersistentMap[K, V]) set(
+	n *persistentNode[K, V], key K, value V,
+) (*persistentNode[K, V], bool) {
+	if n == nil {
+		return &persistentNode[K, V]{key: key, value: value, height: 1}, true
+	}
+	c := *n
+	added := false
+	if m.less(key, n.key) {
+		c.left, added = m.set(n.left, key, value)
+	} else if m.less(n.key, key) {
+		c.right, added = m.set(n.right, key, value)
+	} else {
+		c.key = key
+		c.value = value
+		return &c, false
+	}
+	return c.rebalance(), added
+}
+
+func (m *PersistentMap[K, V]) unset(
+	n *persistentNode[K, V], key K,
+) (*persistentNode[K, V], bool) {
+	if n == nil {
+		return nil, false
+	}
+	if m.less(key, n.key) {
+		left, ok := m.unset(n.left, key)
+		if !ok {
+			return n, false
+		}
+		c := *n
+		c.left = left
+		return c.rebalance(), true
+	}
+	if m.less(n.key, key) {
+		right, ok := m.unset(n.right, key)
+		if !ok {
+			return n, false
+		}
+		c := *n
+		c.right = right
+		return c.rebalance(), true
+	}
+	if n.left == nil {
+		return n.right, true
+	}
+	if n.right == nil {
+		return n.left, true
+	}
+	right, next := n.right.unsetFront()
+	c := *next
+	c.left = n.left
+	c.right = right
+	return c.rebalance(), true
+}
+
+// unsetFront returns copy of subtree without the first node and
+// the first node itself.
+func (n *persistentNode[K, V]) unsetFront() (
+	*persistentNode[K, V], *persistentNode[K, V],
+) {
+	if n.left == nil {
+		return n.right, n
+	}
+	left, front := n.left.unsetFront()
+	c := *n
+	c.left = left
+	return c.rebalance(), front
+}
+
+// rebalance rebalances node that is not shared with other versions.
+func (n *persistentNode[K, V]) rebalance() *persistentNode[K, V] {
+	n.recalcHeight()
+	if b := n.balance(); b > 1 {
+		if n.left.balance() < 0 {
+			left := *n.left
+			n.left = left.leftRotate()
+		}
+		return n.rightRotate()
+	} else if b < -1 {
+		if n.right.balance() > 0 {
+			right := *n.right
+			n.right = right.rightRotate()
+		}
+		return n.leftRotate()
+	}
+	return n
+}
+
+func (n *persistentNode[K, V]) balance() int8 {
+	var b int8
+	if n.left != nil {
+		b += n.left.height
+	}
+	if n.right != nil {
+		b -= n.right.height
+	}
+	return b
+}
+
+func (n *persistentNode[K, V]) recalcHeight() {
+	n.height = 1
+	if n.left != nil {
+		n.height = n.left.height + 1
+	}
+	if n.right != nil && n.right.height >= n.height {
+		n.height = n.right.height + 1
+	}
+}
+
+// leftRotate rotates node that is not shared with other versions.
+func (n *persistentNode[K, V]) leftRotate() *persistentNode[K, V] {
+	x := *n.right
+	n.right = x.left
+	x.left = n
+	n.recalcHeight()
+	x.recalcHeight()
+	return &x
+}
+
+// rightRotate rotates node that is not shared with other versions.
+func (n *persistentNode[K, V]) rightRotate() *persistentNode[K, V] {
+	x := *n.left
+	n.left = x.right
+	x.right = n
+	n.recalcHeight()
+	x.recalcHeight()
+	return &x
+}
+
+// PersistentCursor represents cursor over version of persistent map.
+//
+// Persistent nodes have no links to parents, so cursor keeps path
+// from root to current node.
+type PersistentCursor[K, V any] struct {
+	m     *PersistentMap[K, V]
+	stack []*persistentNode[K, V]
+}
+
+// First moves cursor to the first element, or returns false if map
+// is empty.
+func (c *PersistentCursor[K, V]) First() bool {
+	c.stack = c.stack[:0]
+	c.pushFront(c.m.root)
+	return len(c.stack) > 0
+}
+
+// Last moves cursor to the last element, or returns false if map
+// is empty.
+func (c *PersistentCursor[K, V]) Last() bool {
+	c.stack = c.stack[:0]
+	c.pushBack(c.m.root)
+	return len(c.stack) > 0
+}
+
+// Next moves cursor forward, or returns false if there is no next
+// element.
+//
+// If cursor is not positioned, Next will move it to the first element.
+func (c *PersistentCursor[K, V]) Next() bool {
+	if len(c.stack) == 0 {
+		return c.First()
+	}
+	if n := c.stack[len(c.stack)-1]; n.right != nil {
+		c.pushFront(n.right)
+		return true
+	}
+	for {
+		n := c.stack[len(c.stack)-1]
+		c.stack = c.stack[:len(c.stack)-1]
+		if len(c.stack) == 0 {
+			return false
+		}
+		if c.stack[len(c.stack)-1].left == n {
+			return true
+		}
+	}
+}
+
+// Prev moves cursor backward, or returns false if there is no previous
+// element.
+//
+// If cursor is not positioned, Prev will move it to the last element.
+func (c *PersistentCursor[K, V]) Prev() bool {
+	if len(c.stack) == 0 {
+		return c.Last()
+	}
+	if n := c.stack[len(c.stack)-1]; n.left != nil {
+		c.pushBack(n.left)
+		return true
+	}
+	for {
+		n := c.stack[len(c.stack)-1]
+		c.stack = c.stack[:len(c.stack)-1]
+		if len(c.stack) == 0 {
+			return false
+		}
+		if c.stack[len(c.stack)-1].right == n {
+			return true
+		}
+	}
+}
+
+// Seek moves cursor to the smallest element with element.key >= key,
+// or returns false if there is no such element.
+func (c *PersistentCursor[K, V]) Seek(key K) bool {
+	c.stack = c.stack[:0]
+	pos := 0
+	for it := c.m.root; it != nil; {
+		c.stack = append(c.stack, it)
+		if c.m.less(it.key, key) {
+			it = it.right
+		} else {
+			pos = len(c.stack)
+			it = it.left
+		}
+	}
+	c.stack = c.stack[:pos]
+	return pos > 0
+}
+
+// SeekPrev moves cursor to the largest element with element.key <= key,
+// or returns false if there is no such element.
+func (c *PersistentCursor[K, V]) SeekPrev(key K) bool {
+	c.stack = c.stack[:0]
+	pos := 0
+	for it := c.m.root; it != nil; {
+		c.stack = append(c.stack, it)
+		if c.m.less(key, it.key) {
+			it = it.left
+		} else {
+			pos = len(c.stack)
+			it = it.right
+		}
+	}
+	c.stack = c.stack[:pos]
+	return pos > 0
+}
+
+// Key returns key of current element.
+func (c *PersistentCursor[K, V]) Key() K {
+	return c.stack[len(c.stack)-1].key
+}
+
+// Value returns value of current element.
+func (c *PersistentCursor[K, V]) Value() V {
+	return c.stack[len(c.stack)-1].value
+}
+
+func (c *PersistentCursor[K, V]) pushFront(n *persistentNode[K, V]) {
+	for ; n != nil; n = n.left {
+		c.stack = append(c.stack, n)
+	}
+}
+
+func (c *PersistentCursor[K, V]) pushBack(n *persistentNode[K, V]) {
+	for ; n != nil; n = n.right {
+		c.stack = append(c.stack, n)
+	}
+}
@@ -0,0 +1,155 @@
+package avltree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func testCheckPersistent(tb testing.TB, n *persistentNode[int, int]) int8 {
+	if n == nil {
+		return 0
+	}
+	lh := testCheckPersistent(tb, n.left)
+	rh := testCheckPersistent(tb, n.right)
+	if lh-rh > 1 || rh-lh > 1 {
+		tb.Fatal("Tree is not balanced")
+	}
+	h := lh + 1
+	if rh >= lh {
+		h = rh + 1
+	}
+	if n.height != h {
+		tb.Fatalf("Expected height = %d, got %d", h, n.height)
+	}
+	return h
+}
+
+func testCheckPersistentMap(
+	tb testing.TB, m *PersistentMap[int, int], values map[int]int,
+) {
+	testCheckPersistent(tb, m.root)
+	if v := m.Len(); v != len(values) {
+		tb.Fatalf("Expected len = %d, got %d", len(values), v)
+	}
+	var keys []int
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	c := m.Cursor()
+	for _, k := range keys {
+		if !c.Next() {
+			tb.Fatal("Unexpected end of cursor")
+		}
+		if v := c.Key(); v != k {
+			tb.Fatalf("Expected key = %d, got %d", k, v)
+		}
+		if v := c.Value(); v != values[k] {
+			tb.Fatalf("Expected value = %d, got %d", values[k], v)
+		}
+		if v, ok := m.Get(k); !ok || v != values[k] {
+			tb.Fatalf("Expected value = %d, got %d", values[k], v)
+		}
+	}
+	if c.Next() {
+		tb.Fatal("Cursor should be ended")
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !c.Prev() {
+			tb.Fatal("Unexpected end of cursor")
+		}
+		if v := c.Key(); v != keys[i] {
+			tb.Fatalf("Expected key = %d, got %d", keys[i], v)
+		}
+	}
+	if c.Prev() {
+		tb.Fatal("Cursor should be ended")
+	}
+}
+
+func TestPersistentMap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	n := 300
+	versions := []*PersistentMap[int, int]{NewPersistentMap[int, int](intLess)}
+	values := []map[int]int{{}}
+	for i := 0; i < 3*n; i++ {
+		m := versions[len(versions)-1]
+		v := map[int]int{}
+		for k, x := range values[len(values)-1] {
+			v[k] = x
+		}
+		k := rnd.Intn(n)
+		if rnd.Intn(3) == 0 {
+			m = m.Unset(k)
+			delete(v, k)
+		} else {
+			m = m.Set(k, i)
+			v[k] = i
+		}
+		versions = append(versions, m)
+		values = append(values, v)
+	}
+	for i := range versions {
+		testCheckPersistentMap(t, versions[i], values[i])
+	}
+}
+
+func TestPersistentCursorSeek(t *testing.T) {
+	m := NewPersistentMap[int, int](intLess)
+	n := 100
+	for i := 0; i < n; i++ {
+		m = m.Set(i*2, i)
+	}
+	c := m.Cursor()
+	for i := -1; i < 2*n; i++ {
+		if !c.Seek(i) {
+			if i+1 < 2*n {
+				t.Fatalf("Unable to seek %d", i)
+			}
+		} else if v := c.Key(); v != i+(i+2)%2 {
+			t.Fatalf("Expected key = %d, got %d", i+(i+2)%2, v)
+		}
+		if !c.SeekPrev(i) {
+			if i >= 0 {
+				t.Fatalf("Unable to seek %d", i)
+			}
+		} else if v := c.Key(); v != i-i%2 {
+			t.Fatalf("Expected key = %d, got %d", i-i%2, v)
+		}
+	}
+	if !c.Seek(10) || !c.Next() || c.Key() != 12 {
+		t.Fatal("Invalid next key")
+	}
+	if !c.SeekPrev(11) || !c.Prev() || c.Key() != 8 {
+		t.Fatal("Invalid prev key")
+	}
+	if m.Unset(1) != m {
+		t.Fatal("Expected same version")
+	}
+}
+
+func BenchmarkAvltreePersistentMapSet(b *testing.B) {
+	rnd := rand.New(rand.NewSource(42))
+	b.ResetTimer()
+	m := NewPersistentMap[int, int](intLess)
+	p := rnd.Perm(b.N)
+	for i := 0; i < b.N; i++ {
+		m = m.Set(p[i], i)
+	}
+}
+
+func BenchmarkAvltreePersistentMapGet(b *testing.B) {
+	m := NewPersistentMap[int, int](intLess)
+	for i := 0; i < b.N; i++ {
+		m = m.Set(i, i)
+	}
+	rnd := rand.New(rand.NewSource(42))
+	b.ResetTimer()
+	p := rnd.Perm(b.N)
+	for i := 0; i < b.N; i++ {
+		if _, ok := m.Get(p[i]); !ok {
+			b.Fatalf("Unable to find key = %d", p[i])
+		}
+	}
+}
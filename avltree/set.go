@@ -0,0 +1,140 @@
+package avltree
+
+import "iter"
+
+// Set represents ordered set of keys using AVL tree.
+//
+// Set shares implementation with Map, but values of empty type have
+// zero size, so nodes do not use memory for values.
+type Set[K any] struct {
+	tree Map[K, struct{}]
+}
+
+// Add adds key to set and returns false if key already exists.
+func (s *Set[K]) Add(key K) bool {
+	n, parent, left := s.tree.lookup(key)
+	if n != nil {
+		return false
+	}
+	s.tree.attach(&Node[K, struct{}]{key: key}, parent, left)
+	return true
+}
+
+// Has returns true if set contains key.
+func (s *Set[K]) Has(key K) bool {
+	return s.tree.Find(key) != nil
+}
+
+// Remove removes key from set and returns false if there is no
+// such key.
+func (s *Set[K]) Remove(key K) bool {
+	n := s.tree.Find(key)
+	if n == nil {
+		return false
+	}
+	s.tree.Erase(n)
+	return true
+}
+
+// Len returns amount of keys in set.
+func (s *Set[K]) Len() int {
+	return s.tree.len
+}
+
+// Seek returns the smallest key >= key.
+//
+// If there is no such key, ok will be false.
+func (s *Set[K]) Seek(key K) (result K, ok bool) {
+	if n := s.tree.LowerBound(key); n != nil {
+		return n.key, true
+	}
+	return
+}
+
+// SeekPrev returns the largest key <= key.
+//
+// If there is no such key, ok will be false.
+func (s *Set[K]) SeekPrev(key K) (result K, ok bool) {
+	n := s.tree.UpperBound(key)
+	if n != nil {
+		n = n.Prev()
+	} else {
+		n = s.tree.Back()
+	}
+	if n != nil {
+		return n.key, true
+	}
+	return
+}
+
+// All returns iterator over all keys of set in ascending order.
+//
+// Set can be modified during iteration in the same way as Map.
+func (s *Set[K]) All() iter.Seq[K] {
+	return s.tree.Keys()
+}
+
+// Backward returns iterator over all keys of set in descending order.
+func (s *Set[K]) Backward() iter.Seq[K] {
+	return setKeys(s.tree.Backward())
+}
+
+// Range returns iterator over keys with lo <= key < hi in ascending
+// order.
+func (s *Set[K]) Range(lo, hi K) iter.Seq[K] {
+	return setKeys(s.tree.Range(lo, hi))
+}
+
+// RangeFrom returns iterator over keys with key >= lo in ascending
+// order.
+func (s *Set[K]) RangeFrom(lo K) iter.Seq[K] {
+	return setKeys(s.tree.RangeFrom(lo))
+}
+
+// Union adds all keys of other set.
+func (s *Set[K]) Union(other *Set[K]) {
+	s.tree.Union(&other.tree, setResolve[K])
+}
+
+// Intersection removes keys that are missing in other set.
+func (s *Set[K]) Intersection(other *Set[K]) {
+	s.tree.Intersection(&other.tree, setResolve[K])
+}
+
+// Difference removes keys that are present in other set.
+func (s *Set[K]) Difference(other *Set[K]) {
+	s.tree.Difference(&other.tree)
+}
+
+// SymmetricDifference removes keys that are present in other set and
+// adds keys of other set that are missing.
+func (s *Set[K]) SymmetricDifference(other *Set[K]) {
+	s.tree.SymmetricDifference(&other.tree)
+}
+
+// Clone creates copy of set.
+func (s *Set[K]) Clone() *Set[K] {
+	return &Set[K]{tree: *s.tree.Clone()}
+}
+
+// NewSet creates new instance of ordered set.
+func NewSet[K any](less func(K, K) bool) *Set[K] {
+	s := Set[K]{}
+	s.tree.less = less
+	return &s
+}
+
+func setResolve[K any](K, struct{}, struct{}) struct{} {
+	return struct{}{}
+}
+
+// setKeys returns iterator over keys of elements.
+func setKeys[K any](seq iter.Seq2[K, struct{}]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range seq {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package avltree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func TestSet(t *testing.T) {
+	s := NewSet[int](intLess)
+	rnd := rand.New(rand.NewSource(42))
+	expected := map[int]bool{}
+	for i := 0; i < 10000; i++ {
+		k := rnd.Intn(1000)
+		if rnd.Intn(3) == 0 {
+			if s.Remove(k) != expected[k] {
+				t.Fatalf("Invalid removal of key %d", k)
+			}
+			delete(expected, k)
+		} else {
+			if s.Add(k) == expected[k] {
+				t.Fatalf("Invalid addition of key %d", k)
+			}
+			expected[k] = true
+		}
+		if v := s.Len(); v != len(expected) {
+			t.Fatalf("Expected len = %d, got %d", len(expected), v)
+		}
+	}
+	keys := slices.Collect(s.All())
+	if len(keys) != len(expected) || !slices.IsSorted(keys) {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	for _, k := range keys {
+		if !s.Has(k) || !expected[k] {
+			t.Fatalf("Key %d should exist", k)
+		}
+	}
+	backward := slices.Collect(s.Backward())
+	slices.Reverse(backward)
+	if !slices.Equal(backward, keys) {
+		t.Fatalf("Invalid keys: %v", backward)
+	}
+	var rng []int
+	for k := range s.Range(100, 200) {
+		rng = append(rng, k)
+	}
+	for k := range s.RangeFrom(900) {
+		rng = append(rng, k)
+	}
+	var want []int
+	for _, k := range keys {
+		if k >= 100 && k < 200 || k >= 900 {
+			want = append(want, k)
+		}
+	}
+	if !slices.Equal(rng, want) {
+		t.Fatalf("Invalid keys: %v", rng)
+	}
+	for k := -1; k <= 1000; k++ {
+		i, _ := slices.BinarySearch(keys, k)
+		if v, ok := s.Seek(k); ok != (i < len(keys)) || ok && v != keys[i] {
+			t.Fatalf("Invalid seek of key %d", k)
+		}
+		j, found := slices.BinarySearch(keys, k)
+		if !found {
+			j--
+		}
+		if v, ok := s.SeekPrev(k); ok != (j >= 0) || ok && v != keys[j] {
+			t.Fatalf("Invalid seek of key %d", k)
+		}
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	for _, n := range []int{0, 10, 1000} {
+		x, y := NewSet[int](intLess), NewSet[int](intLess)
+		xs, ys := map[int]bool{}, map[int]bool{}
+		for i := 0; i < n; i++ {
+			k := rnd.Intn(n * 2)
+			x.Add(k)
+			xs[k] = true
+			k = rnd.Intn(n * 2)
+			y.Add(k)
+			ys[k] = true
+		}
+		check := func(s *Set[int], fn func(k int) bool) {
+			count := 0
+			for k := 0; k < n*2; k++ {
+				if fn(k) {
+					count++
+				}
+				if s.Has(k) != fn(k) {
+					t.Fatalf("Invalid existence of key %d", k)
+				}
+			}
+			if v := s.Len(); v != count {
+				t.Fatalf("Expected len = %d, got %d", count, v)
+			}
+		}
+		s := x.Clone()
+		s.Union(y)
+		check(s, func(k int) bool { return xs[k] || ys[k] })
+		s = x.Clone()
+		s.Intersection(y)
+		check(s, func(k int) bool { return xs[k] && ys[k] })
+		s = x.Clone()
+		s.Difference(y)
+		check(s, func(k int) bool { return xs[k] && !ys[k] })
+		s = x.Clone()
+		s.SymmetricDifference(y)
+		check(s, func(k int) bool { return xs[k] != ys[k] })
+		check(x, func(k int) bool { return xs[k] })
+		check(y, func(k int) bool { return ys[k] })
+	}
+}
+
+func TestSetNodeSize(t *testing.T) {
+	if v, w := unsafe.Sizeof(Node[int, struct{}]{}), unsafe.Sizeof(Node[int, int]{}); v >= w {
+		t.Fatalf("Expected node size < %d, got %d", w, v)
+	}
+}
@@ -0,0 +1,79 @@
+package avltree
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/udovin/algo/ordered"
+)
+
+var (
+	// ErrNotSorted is the same error as ordered.ErrNotSorted.
+	ErrNotSorted = ordered.ErrNotSorted
+	// ErrDuplicateKey is the same error as ordered.ErrDuplicateKey.
+	ErrDuplicateKey = ordered.ErrDuplicateKey
+)
+
+// FromSorted creates map from keys sorted in ascending order and
+// corresponding values.
+//
+// Keys should be unique, otherwise FromSorted will return error.
+// FromSorted takes O(n) time and creates perfectly balanced tree.
+func FromSorted[K, V any](less func(K, K) bool, keys []K, values []V) (*Map[K, V], error) {
+	if len(keys) != len(values) {
+		panic("keys and values have different lengths")
+	}
+	for i := 1; i < len(keys); i++ {
+		if err := checkSorted(less, keys[i-1], keys[i]); err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+	}
+	m := NewMap[K, V](less)
+	m.root = m.build(keys, values)
+	m.len = len(keys)
+	return m, nil
+}
+
+// FromSortedSeq creates map from sequence of items sorted by key
+// in ascending order.
+//
+// Keys should be unique, otherwise FromSortedSeq will return error.
+// FromSortedSeq takes O(n) time and creates perfectly balanced tree.
+func FromSortedSeq[K, V any](less func(K, K) bool, seq iter.Seq2[K, V]) (*Map[K, V], error) {
+	var keys []K
+	var values []V
+	for key, value := range seq {
+		if len(keys) > 0 {
+			if err := checkSorted(less, keys[len(keys)-1], key); err != nil {
+				return nil, fmt.Errorf("key %d: %w", len(keys), err)
+			}
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	m := NewMap[K, V](less)
+	m.root = m.build(keys, values)
+	m.len = len(keys)
+	return m, nil
+}
+
+func checkSorted[K any](less func(K, K) bool, prev, key K) error {
+	if less(key, prev) {
+		return ErrNotSorted
+	}
+	if !less(prev, key) {
+		return ErrDuplicateKey
+	}
+	return nil
+}
+
+// build creates perfectly balanced tree from sorted items.
+func (m *Map[K, V]) build(keys []K, values []V) *Node[K, V] {
+	if len(keys) == 0 {
+		return nil
+	}
+	mid := len(keys) / 2
+	n := &Node[K, V]{key: keys[mid], value: values[mid]}
+	m.link(n, m.build(keys[:mid], values[:mid]), m.build(keys[mid+1:], values[mid+1:]))
+	return n
+}
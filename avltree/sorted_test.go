@@ -0,0 +1,68 @@
+package avltree
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/udovin/algo/ordered"
+)
+
+func TestFromSorted(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 7, 100, 1000} {
+		keys := make([]int, n)
+		values := make([]int, n)
+		expected := map[int]int{}
+		for i := range keys {
+			keys[i] = i * 2
+			values[i] = i
+			expected[i*2] = i
+		}
+		m, err := FromSorted(intLess, keys, values)
+		if err != nil {
+			t.Fatal("Error:", err)
+		}
+		testCheckValues(t, m, expected)
+		seq := func(yield func(int, int) bool) {
+			for i := range keys {
+				if !yield(keys[i], values[i]) {
+					return
+				}
+			}
+		}
+		m, err = FromSortedSeq(intLess, seq)
+		if err != nil {
+			t.Fatal("Error:", err)
+		}
+		testCheckValues(t, m, expected)
+	}
+}
+
+func TestFromSortedErrors(t *testing.T) {
+	if _, err := FromSorted(intLess, []int{1, 3, 2}, []int{1, 2, 3}); !errors.Is(err, ErrNotSorted) {
+		t.Fatalf("Expected %v, got %v", ErrNotSorted, err)
+	}
+	if _, err := FromSorted(intLess, []int{1, 2, 2}, []int{1, 2, 3}); !errors.Is(err, ordered.ErrDuplicateKey) {
+		t.Fatalf("Expected %v, got %v", ordered.ErrDuplicateKey, err)
+	}
+	seq := func(yield func(int, int) bool) {
+		for _, k := range []int{1, 2, 3, 0} {
+			if !yield(k, k) {
+				return
+			}
+		}
+	}
+	if _, err := FromSortedSeq(intLess, seq); !errors.Is(err, ErrNotSorted) {
+		t.Fatalf("Expected %v, got %v", ErrNotSorted, err)
+	}
+}
+
+func BenchmarkAvltreeSimpleIntMapFromSorted(b *testing.B) {
+	keys := make([]int, b.N)
+	for i := range keys {
+		keys[i] = i
+	}
+	b.ResetTimer()
+	if _, err := FromSorted(intLess, keys, keys); err != nil {
+		b.Fatal("Error:", err)
+	}
+}
@@ -0,0 +1,200 @@
+package btree
+
+import "math/bits"
+
+func (m *mapImpl[K, V]) Union(other Map[K, V], resolve func(key K, x, y V) V) {
+	if m.isSmall(other) {
+		for key, y := range other.All() {
+			m.compute(key, func(x V, exists bool) (V, bool) {
+				if exists {
+					return resolve(key, x, y), true
+				}
+				return y, true
+			}, true)
+		}
+		return
+	}
+	b := mapBuilder[K, V]{}
+	b.grow(m.len + other.Len())
+	m.merge(other, func(kind mergeKind, key K, x, y V) {
+		switch kind {
+		case mergeLeft:
+			b.append(key, x)
+		case mergeRight:
+			b.append(key, y)
+		default:
+			b.append(key, resolve(key, x, y))
+		}
+	})
+	b.build(m)
+}
+
+func (m *mapImpl[K, V]) Intersection(other Map[K, V], resolve func(key K, x, y V) V) {
+	b := mapBuilder[K, V]{}
+	if m.isSmall(other) {
+		it := m.Iter()
+		for key, y := range other.All() {
+			if it.Seek(key) && !m.less(key, it.Key()) {
+				b.append(it.Key(), resolve(it.Key(), it.Value(), y))
+			}
+		}
+		b.build(m)
+		return
+	}
+	m.merge(other, func(kind mergeKind, key K, x, y V) {
+		if kind == mergeBoth {
+			b.append(key, resolve(key, x, y))
+		}
+	})
+	b.build(m)
+}
+
+func (m *mapImpl[K, V]) Difference(other Map[K, V]) {
+	if m.isSmall(other) {
+		for key := range other.All() {
+			m.Delete(key)
+		}
+		return
+	}
+	b := mapBuilder[K, V]{}
+	m.merge(other, func(kind mergeKind, key K, x, y V) {
+		if kind == mergeLeft {
+			b.append(key, x)
+		}
+	})
+	b.build(m)
+}
+
+func (m *mapImpl[K, V]) SymmetricDifference(other Map[K, V]) {
+	if m.isSmall(other) {
+		for key, y := range other.All() {
+			m.compute(key, func(x V, exists bool) (V, bool) {
+				return y, !exists
+			}, true)
+		}
+		return
+	}
+	b := mapBuilder[K, V]{}
+	m.merge(other, func(kind mergeKind, key K, x, y V) {
+		switch kind {
+		case mergeLeft:
+			b.append(key, x)
+		case mergeRight:
+			b.append(key, y)
+		}
+	})
+	b.build(m)
+}
+
+// isSmall returns true if other map is small enough to apply its items
+// one by one in O(k log n) time instead of merge of both maps and
+// rebuild of map in O(n + k) time.
+func (m *mapImpl[K, V]) isSmall(other Map[K, V]) bool {
+	return other.Len()*bits.Len(uint(m.len)) < m.len
+}
+
+type mergeKind int
+
+const (
+	mergeLeft mergeKind = iota
+	mergeRight
+	mergeBoth
+)
+
+// merge calls fn for each key of both maps in ascending order.
+//
+// Kind of call shows in which map key is present. Values of missing
+// keys are empty.
+func (m *mapImpl[K, V]) merge(other Map[K, V], fn func(kind mergeKind, key K, x, y V)) {
+	var empty V
+	lit, rit := m.Iter(), other.Iter()
+	lok, rok := lit.Next(), rit.Next()
+	for lok && rok {
+		if m.less(lit.Key(), rit.Key()) {
+			fn(mergeLeft, lit.Key(), lit.Value(), empty)
+			lok = lit.Next()
+		} else if m.less(rit.Key(), lit.Key()) {
+			fn(mergeRight, rit.Key(), empty, rit.Value())
+			rok = rit.Next()
+		} else {
+			fn(mergeBoth, lit.Key(), lit.Value(), rit.Value())
+			lok, rok = lit.Next(), rit.Next()
+		}
+	}
+	for ; lok; lok = lit.Next() {
+		fn(mergeLeft, lit.Key(), lit.Value(), empty)
+	}
+	for ; rok; rok = rit.Next() {
+		fn(mergeRight, rit.Key(), empty, rit.Value())
+	}
+}
+
+// mapBuilder builds map from items sorted by key in linear time.
+type mapBuilder[K, V any] struct {
+	keys   []K
+	values []V
+}
+
+func (b *mapBuilder[K, V]) grow(n int) {
+	b.keys = make([]K, 0, n)
+	b.values = make([]V, 0, n)
+}
+
+func (b *mapBuilder[K, V]) append(key K, value V) {
+	b.keys = append(b.keys, key)
+	b.values = append(b.values, value)
+}
+
+// build replaces all items of map with appended items.
+//
+// Items are distributed between nodes of each level evenly, so all
+// nodes except root are filled at least by half.
+func (b *mapBuilder[K, V]) build(m *mapImpl[K, V]) {
+	m.version++
+	m.root = nil
+	m.len = len(b.keys)
+	if m.len == 0 {
+		return
+	}
+	keys, values := b.keys, b.values
+	var children []*mapNode[K, V]
+	var counts []int
+	for {
+		// Each node except the last one is followed by separator.
+		count := (len(keys) + m.maxLen + 1) / (m.maxLen + 1)
+		size := len(keys) - count + 1
+		nodes := make([]*mapNode[K, V], count)
+		sizes := make([]int, count)
+		sepKeys := make([]K, 0, count-1)
+		sepValues := make([]V, 0, count-1)
+		pos, child := 0, 0
+		for j := range nodes {
+			n := m.newNode(children != nil)
+			n.len = size / count
+			if j < size%count {
+				n.len++
+			}
+			copy(n.keys, keys[pos:pos+n.len])
+			copy(n.values, values[pos:pos+n.len])
+			if children != nil {
+				copy(n.children, children[child:child+n.len+1])
+				copy(n.counts, counts[child:child+n.len+1])
+				child += n.len + 1
+			}
+			pos += n.len
+			// Item after node becomes separator on the next level.
+			if j+1 < count {
+				sepKeys = append(sepKeys, keys[pos])
+				sepValues = append(sepValues, values[pos])
+				pos++
+			}
+			nodes[j] = n
+			sizes[j] = n.size()
+		}
+		if count == 1 {
+			m.root = nodes[0]
+			return
+		}
+		keys, values, children, counts = sepKeys, sepValues, nodes, sizes
+	}
+}
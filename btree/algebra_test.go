@@ -0,0 +1,121 @@
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func testRandomMap(rnd *rand.Rand, n, keys int) (Map[int, int], map[int]int) {
+	m := NewMap[int, int](intLess)
+	values := map[int]int{}
+	for i := 0; i < n; i++ {
+		k := rnd.Intn(keys)
+		v := rnd.Intn(1000)
+		m.Set(k, v)
+		values[k] = v
+	}
+	return m, values
+}
+
+func testCheckValues(tb testing.TB, m Map[int, int], values map[int]int) {
+	testCheckMap(tb, m)
+	if v := m.Len(); v != len(values) {
+		tb.Fatalf("Expected len = %d, got %d", len(values), v)
+	}
+	for k, v := range values {
+		if x, ok := m.Get(k); !ok || x != v {
+			tb.Fatalf("Expected value = %d, got %d", v, x)
+		}
+	}
+}
+
+func testCloneMap(m Map[int, int]) Map[int, int] {
+	c := NewMap[int, int](intLess)
+	for it := m.Iter(); it.Next(); {
+		c.Set(it.Key(), it.Value())
+	}
+	return c
+}
+
+func TestSetAlgebra(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	resolve := func(key, x, y int) int {
+		return x*1000 + y
+	}
+	sizes := []int{0, 1, 10, 100, 1000, 10000}
+	for _, n1 := range sizes {
+		for _, n2 := range sizes {
+			x, xv := testRandomMap(rnd, n1, 10000)
+			y, yv := testRandomMap(rnd, n2, 10000)
+			union := map[int]int{}
+			intersection := map[int]int{}
+			difference := map[int]int{}
+			symmetric := map[int]int{}
+			for k, v := range xv {
+				if w, ok := yv[k]; ok {
+					union[k] = resolve(k, v, w)
+					intersection[k] = resolve(k, v, w)
+				} else {
+					union[k] = v
+					difference[k] = v
+					symmetric[k] = v
+				}
+			}
+			for k, v := range yv {
+				if _, ok := xv[k]; !ok {
+					union[k] = v
+					symmetric[k] = v
+				}
+			}
+			{
+				c := testCloneMap(x)
+				c.Union(y, resolve)
+				testCheckValues(t, c, union)
+			}
+			{
+				c := testCloneMap(x)
+				c.Intersection(y, resolve)
+				testCheckValues(t, c, intersection)
+			}
+			{
+				c := testCloneMap(x)
+				c.Difference(y)
+				testCheckValues(t, c, difference)
+			}
+			{
+				c := testCloneMap(x)
+				c.SymmetricDifference(y)
+				testCheckValues(t, c, symmetric)
+			}
+			testCheckValues(t, x, xv)
+			testCheckValues(t, y, yv)
+		}
+	}
+}
+
+func TestSetAlgebraSelf(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	x, xv := testRandomMap(rnd, 1000, 10000)
+	x.Union(x, func(key, x, y int) int {
+		return x + y
+	})
+	for k, v := range xv {
+		xv[k] = v * 2
+	}
+	testCheckValues(t, x, xv)
+	x.SymmetricDifference(x)
+	testCheckValues(t, x, map[int]int{})
+}
+
+func BenchmarkBtreeSimpleIntMapUnion(b *testing.B) {
+	rnd := rand.New(rand.NewSource(42))
+	m, _ := testRandomMap(rnd, 100000, 1000000)
+	o, _ := testRandomMap(rnd, 100000, 1000000)
+	resolve := func(key, x, y int) int {
+		return y
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Union(o, resolve)
+	}
+}
@@ -0,0 +1,74 @@
+package btree
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrCorrupted means that encoded data is invalid.
+var ErrCorrupted = errors.New("data is corrupted")
+
+// Codec represents binary encoding of keys or values of DiskMap.
+type Codec[T any] interface {
+	// Append appends encoded value to buffer.
+	Append(buf []byte, value T) []byte
+	// Decode decodes value from the beginning of buffer and returns
+	// amount of read bytes.
+	Decode(buf []byte) (T, int, error)
+}
+
+// IntCodec encodes integers using variable-length encoding.
+type IntCodec struct{}
+
+func (IntCodec) Append(buf []byte, value int) []byte {
+	return binary.AppendVarint(buf, int64(value))
+}
+
+func (IntCodec) Decode(buf []byte) (int, int, error) {
+	value, n := binary.Varint(buf)
+	if n <= 0 {
+		return 0, 0, ErrCorrupted
+	}
+	return int(value), n, nil
+}
+
+// StringCodec encodes strings with length prefix.
+type StringCodec struct{}
+
+func (StringCodec) Append(buf []byte, value string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+func (StringCodec) Decode(buf []byte) (string, int, error) {
+	value, n, err := decodeBytes(buf)
+	return string(value), n, err
+}
+
+// BytesCodec encodes byte slices with length prefix.
+//
+// Decoded slices do not share memory with buffer.
+type BytesCodec struct{}
+
+func (BytesCodec) Append(buf []byte, value []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+func (BytesCodec) Decode(buf []byte) ([]byte, int, error) {
+	value, n, err := decodeBytes(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append([]byte{}, value...), n, nil
+}
+
+// decodeBytes decodes slice of buffer with length prefix.
+func decodeBytes(buf []byte) ([]byte, int, error) {
+	size, n := binary.Uvarint(buf)
+	if n <= 0 || size > uint64(len(buf)-n) {
+		return nil, 0, ErrCorrupted
+	}
+	end := n + int(size)
+	return buf[n:end], end, nil
+}
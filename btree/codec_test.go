@@ -0,0 +1,52 @@
+package btree
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testCodec[T any](t *testing.T, codec Codec[T], values []T, equal func(T, T) bool) {
+	var buf []byte
+	for _, value := range values {
+		buf = codec.Append(buf, value)
+	}
+	for _, value := range values {
+		decoded, n, err := codec.Decode(buf)
+		if err != nil {
+			t.Fatal("Error:", err)
+		}
+		if !equal(decoded, value) {
+			t.Fatalf("Expected value = %v, got %v", value, decoded)
+		}
+		buf = buf[n:]
+	}
+	if len(buf) != 0 {
+		t.Fatalf("Expected len = %d, got %d", 0, len(buf))
+	}
+	if _, _, err := codec.Decode(nil); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("Expected error %v, got %v", ErrCorrupted, err)
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	testCodec(t, IntCodec{}, []int{0, 1, -1, 1 << 40, -1 << 62}, func(x, y int) bool {
+		return x == y
+	})
+	testCodec(t, StringCodec{}, []string{"", "a", "tenant/bucket/object"}, func(x, y string) bool {
+		return x == y
+	})
+	testCodec(t, BytesCodec{}, [][]byte{{}, {0}, []byte("value")}, bytes.Equal)
+	buf := StringCodec{}.Append(nil, "value")
+	if _, _, err := (StringCodec{}).Decode(buf[:3]); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("Expected error %v, got %v", ErrCorrupted, err)
+	}
+	value, _, err := BytesCodec{}.Decode(buf)
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	buf[1] = 'x'
+	if string(value) != "value" {
+		t.Fatalf("Decoded value should not share memory with buffer")
+	}
+}
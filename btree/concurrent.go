@@ -0,0 +1,351 @@
+package btree
+
+import (
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ConcurrentMap represents map implementation using B-link tree.
+//
+// ConcurrentMap is safe for concurrent use without external locking.
+// Each node has its own lock, so operations with different keys work
+// concurrently. Lookups hold at most two locks at once while moving
+// right. Propagation of split holds lock of split node until lock of
+// its parent is acquired, and moving right from parent holds one more
+// lock, so Set holds at most three locks at once. Locks are always
+// acquired from left to right and from child to parent.
+// Nodes of each level are linked with right siblings and contain high
+// key, so concurrent split of node is detected and handled by moving
+// right instead of restarting from root.
+//
+// Nodes are not merged on deletion: emptied leaves stay in tree and
+// are reused by following insertions of keys from their ranges.
+type ConcurrentMap[K, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V)
+	Delete(key K)
+	// Len returns amount of items. Concurrent modifications can be
+	// partially reflected.
+	Len() int
+	// All returns iterator over all items in ascending order.
+	//
+	// Iteration is weakly consistent: each item is yielded at most
+	// once, items that are not modified during iteration are always
+	// yielded, and concurrently modified items may be yielded or not.
+	All() iter.Seq2[K, V]
+	// Range returns iterator over items with lo <= key < hi in
+	// ascending order. Iteration is weakly consistent like in All.
+	Range(lo, hi K) iter.Seq2[K, V]
+	// RangeFrom returns iterator over items with key >= lo in
+	// ascending order. Iteration is weakly consistent like in All.
+	RangeFrom(lo K) iter.Seq2[K, V]
+}
+
+func NewConcurrentMap[K, V any](less func(K, K) bool) ConcurrentMap[K, V] {
+	return NewConcurrentMapWithOptions[K, V](less, MapOptions{})
+}
+
+// NewConcurrentMapWithOptions creates new concurrent map with specified
+// options.
+//
+// Degree limits both amount of items in leaves and amount of separator
+// keys in internal nodes.
+func NewConcurrentMapWithOptions[K, V any](less func(K, K) bool, options MapOptions) ConcurrentMap[K, V] {
+	m := concurrentMapImpl[K, V]{
+		less:   less,
+		maxLen: mapMaxLen[K, V](options),
+	}
+	m.root.Store(&concurrentNode[K, V]{})
+	return &m
+}
+
+// concurrentNode represents node of B-link tree.
+//
+// Keys of internal node are separators like in plusNode. All keys of
+// subtree are less than high key, unless node is the rightmost node
+// of its level. Fields of node are protected by its mutex, except
+// level that is never changed.
+type concurrentNode[K, V any] struct {
+	mutex    sync.RWMutex
+	keys     []K
+	values   []V
+	children []*concurrentNode[K, V]
+	// next is right sibling or nil for the rightmost node.
+	next *concurrentNode[K, V]
+	// high is high key, it is valid only if next is not nil.
+	high K
+	// level is height of subtree, leaves have zero level.
+	level int
+}
+
+type concurrentMapImpl[K, V any] struct {
+	root atomic.Pointer[concurrentNode[K, V]]
+	// rootMutex is held during growth of tree.
+	rootMutex sync.Mutex
+	less      func(K, K) bool
+	len       atomic.Int64
+	maxLen    int
+}
+
+func (m *concurrentMapImpl[K, V]) Get(key K) (V, bool) {
+	n := m.findLeaf(key, nil)
+	n.mutex.RLock()
+	n = m.moveRight(n, key, false)
+	defer n.mutex.RUnlock()
+	i, ok := m.searchLeaf(n, key)
+	if !ok {
+		var empty V
+		return empty, false
+	}
+	return n.values[i], true
+}
+
+func (m *concurrentMapImpl[K, V]) Set(key K, value V) {
+	var path []*concurrentNode[K, V]
+	n := m.findLeaf(key, &path)
+	n.mutex.Lock()
+	n = m.moveRight(n, key, true)
+	i, ok := m.searchLeaf(n, key)
+	if ok {
+		n.values[i] = value
+		n.mutex.Unlock()
+		return
+	}
+	n.keys = slices.Insert(n.keys, i, key)
+	n.values = slices.Insert(n.values, i, value)
+	m.len.Add(1)
+	// Split is propagated to parents, lock of child is released only
+	// after lock of parent is acquired.
+	for len(n.keys) > m.maxLen {
+		sep, right := m.split(n)
+		parent := m.parent(n, sep, right, &path)
+		n.mutex.Unlock()
+		if parent == nil {
+			return
+		}
+		i := m.searchChild(parent, sep)
+		parent.keys = slices.Insert(parent.keys, i, sep)
+		parent.children = slices.Insert(parent.children, i+1, right)
+		n = parent
+	}
+	n.mutex.Unlock()
+}
+
+func (m *concurrentMapImpl[K, V]) Delete(key K) {
+	n := m.findLeaf(key, nil)
+	n.mutex.Lock()
+	n = m.moveRight(n, key, true)
+	defer n.mutex.Unlock()
+	i, ok := m.searchLeaf(n, key)
+	if !ok {
+		return
+	}
+	n.keys = slices.Delete(n.keys, i, i+1)
+	n.values = slices.Delete(n.values, i, i+1)
+	m.len.Add(-1)
+}
+
+func (m *concurrentMapImpl[K, V]) Len() int {
+	return int(m.len.Load())
+}
+
+func (m *concurrentMapImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.scan(nil, yield)
+	}
+}
+
+func (m *concurrentMapImpl[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.scan(&lo, func(key K, value V) bool {
+			return m.less(key, hi) && yield(key, value)
+		})
+	}
+}
+
+func (m *concurrentMapImpl[K, V]) RangeFrom(lo K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.scan(&lo, yield)
+	}
+}
+
+// scan calls yield for items with key >= lo, or for all items if lo
+// is nil, until yield returns false.
+//
+// Items of leaf are copied under lock, so yield is called without
+// holding any lock.
+func (m *concurrentMapImpl[K, V]) scan(lo *K, yield func(K, V) bool) {
+	var n *concurrentNode[K, V]
+	if lo != nil {
+		n = m.findLeaf(*lo, nil)
+	} else {
+		n = m.leftmost(0)
+	}
+	var keys []K
+	var values []V
+	for n != nil {
+		n.mutex.RLock()
+		if lo != nil {
+			n = m.moveRight(n, *lo, false)
+		}
+		i := 0
+		if lo != nil {
+			i, _ = m.searchLeaf(n, *lo)
+		}
+		keys = append(keys[:0], n.keys[i:]...)
+		values = append(values[:0], n.values[i:]...)
+		// Next leaf is read together with items, so keys of following
+		// leaves are greater than copied keys even if leaf is split.
+		next := n.next
+		n.mutex.RUnlock()
+		for i := range keys {
+			if !yield(keys[i], values[i]) {
+				return
+			}
+		}
+		n, lo = next, nil
+	}
+}
+
+// findLeaf returns leaf that contained key at the moment of reading
+// without holding its lock.
+//
+// If path is not nil, then for each internal level it contains node
+// from which search moved to lower level, starting from root.
+func (m *concurrentMapImpl[K, V]) findLeaf(key K, path *[]*concurrentNode[K, V]) *concurrentNode[K, V] {
+	return m.findNode(key, 0, path)
+}
+
+// findNode returns node of specified level that contained key
+// at the moment of reading.
+func (m *concurrentMapImpl[K, V]) findNode(key K, level int, path *[]*concurrentNode[K, V]) *concurrentNode[K, V] {
+	n := m.root.Load()
+	for n.level > level {
+		n.mutex.RLock()
+		n = m.moveRight(n, key, false)
+		if path != nil {
+			*path = append(*path, n)
+		}
+		child := n.children[m.searchChild(n, key)]
+		n.mutex.RUnlock()
+		n = child
+	}
+	return n
+}
+
+// leftmost returns the leftmost node of specified level.
+func (m *concurrentMapImpl[K, V]) leftmost(level int) *concurrentNode[K, V] {
+	n := m.root.Load()
+	for n.level > level {
+		n.mutex.RLock()
+		child := n.children[0]
+		n.mutex.RUnlock()
+		n = child
+	}
+	return n
+}
+
+// moveRight moves from locked node to locked node that contains key.
+//
+// Lock of right sibling is acquired before lock of node is released.
+func (m *concurrentMapImpl[K, V]) moveRight(n *concurrentNode[K, V], key K, write bool) *concurrentNode[K, V] {
+	for n.next != nil && !m.less(key, n.high) {
+		next := n.next
+		if write {
+			next.mutex.Lock()
+			n.mutex.Unlock()
+		} else {
+			next.mutex.RLock()
+			n.mutex.RUnlock()
+		}
+		n = next
+	}
+	return n
+}
+
+// parent returns locked parent that should contain separator of split
+// node, or nil if new root is created instead.
+func (m *concurrentMapImpl[K, V]) parent(
+	n *concurrentNode[K, V], sep K, right *concurrentNode[K, V], path *[]*concurrentNode[K, V],
+) *concurrentNode[K, V] {
+	var parent *concurrentNode[K, V]
+	if len(*path) > 0 {
+		parent = (*path)[len(*path)-1]
+		*path = (*path)[:len(*path)-1]
+	} else {
+		m.rootMutex.Lock()
+		if m.root.Load() == n {
+			m.root.Store(&concurrentNode[K, V]{
+				keys:     []K{sep},
+				children: []*concurrentNode[K, V]{n, right},
+				level:    n.level + 1,
+			})
+			m.rootMutex.Unlock()
+			return nil
+		}
+		m.rootMutex.Unlock()
+		// Tree has grown after node was found, so parent is searched
+		// from new root.
+		parent = m.findNode(sep, n.level+1, nil)
+	}
+	parent.mutex.Lock()
+	return m.moveRight(parent, sep, true)
+}
+
+// split moves the second half of locked node to new right sibling
+// and returns separator and right sibling.
+func (m *concurrentMapImpl[K, V]) split(n *concurrentNode[K, V]) (K, *concurrentNode[K, V]) {
+	i := len(n.keys) / 2
+	right := concurrentNode[K, V]{
+		next:  n.next,
+		high:  n.high,
+		level: n.level,
+	}
+	sep := n.keys[i]
+	if n.children == nil {
+		right.keys = slices.Clone(n.keys[i:])
+		right.values = slices.Clone(n.values[i:])
+		clear(n.values[i:])
+		n.values = n.values[:i]
+	} else {
+		right.keys = slices.Clone(n.keys[i+1:])
+		right.children = slices.Clone(n.children[i+1:])
+		clear(n.children[i+1:])
+		n.children = n.children[:i+1]
+	}
+	clear(n.keys[i:])
+	n.keys = n.keys[:i]
+	n.next = &right
+	n.high = sep
+	return sep, &right
+}
+
+// searchLeaf returns `pos` that `keys[pos] >= key` and flag that `keys[pos] == key`.
+func (m *concurrentMapImpl[K, V]) searchLeaf(n *concurrentNode[K, V], key K) (int, bool) {
+	low, high := 0, len(n.keys)
+	for low < high {
+		mid := (low + high) / 2
+		if m.less(n.keys[mid], key) {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	return low, low < len(n.keys) && !m.less(key, n.keys[low])
+}
+
+// searchChild returns index of child that can contain key.
+func (m *concurrentMapImpl[K, V]) searchChild(n *concurrentNode[K, V], key K) int {
+	low, high := 0, len(n.keys)
+	for low < high {
+		mid := (low + high) / 2
+		if m.less(key, n.keys[mid]) {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return low
+}
@@ -0,0 +1,394 @@
+package btree
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// testCheckConcurrentMap checks that all splits are propagated to
+// parents, keys are sorted, high keys match separators of parents
+// and nodes of each level are linked in order.
+func testCheckConcurrentMap(tb testing.TB, m ConcurrentMap[int, int]) {
+	impl := m.(*concurrentMapImpl[int, int])
+	root := impl.root.Load()
+	levels := make([][]*concurrentNode[int, int], root.level+1)
+	count := 0
+	var check func(n *concurrentNode[int, int], level int, lo, hi *int)
+	check = func(n *concurrentNode[int, int], level int, lo, hi *int) {
+		if n.level != level {
+			tb.Fatalf("Expected level = %d, got %d", level, n.level)
+		}
+		if len(n.keys) > impl.maxLen {
+			tb.Fatalf("Invalid node len = %d", len(n.keys))
+		}
+		if (hi == nil) != (n.next == nil) || (hi != nil && n.high != *hi) {
+			tb.Fatal("Invalid high key")
+		}
+		for i, key := range n.keys {
+			if (i > 0 && n.keys[i-1] >= key) ||
+				(lo != nil && key < *lo) ||
+				(hi != nil && key >= *hi) {
+				tb.Fatalf("Key %d is out of order", key)
+			}
+		}
+		levels[level] = append(levels[level], n)
+		if level == 0 {
+			if n.children != nil || len(n.values) != len(n.keys) {
+				tb.Fatal("Invalid leaf")
+			}
+			count += len(n.keys)
+			return
+		}
+		if len(n.children) != len(n.keys)+1 {
+			tb.Fatal("Invalid internal node")
+		}
+		for i, child := range n.children {
+			clo, chi := lo, hi
+			if i > 0 {
+				clo = &n.keys[i-1]
+			}
+			if i < len(n.keys) {
+				chi = &n.keys[i]
+			}
+			check(child, level-1, clo, chi)
+		}
+	}
+	check(root, root.level, nil, nil)
+	if count != m.Len() {
+		tb.Fatalf("Expected len = %d, got %d", count, m.Len())
+	}
+	for _, nodes := range levels {
+		for i, n := range nodes {
+			if i+1 < len(nodes) && n.next != nodes[i+1] {
+				tb.Fatal("Invalid link to right sibling")
+			}
+		}
+	}
+}
+
+func TestConcurrentMap(t *testing.T) {
+	for _, degree := range []int{2, 3, 16} {
+		t.Run(fmt.Sprintf("Degree%d", degree), func(t *testing.T) {
+			m := NewConcurrentMapWithOptions[int, int](intLess, MapOptions{Degree: degree})
+			expected := map[int]int{}
+			rnd := rand.New(rand.NewSource(42))
+			for i := 0; i < 10000; i++ {
+				key := rnd.Intn(1000)
+				if rnd.Intn(3) == 0 {
+					m.Delete(key)
+					delete(expected, key)
+				} else {
+					m.Set(key, i)
+					expected[key] = i
+				}
+				if i%1000 == 0 {
+					testCheckConcurrentMap(t, m)
+				}
+			}
+			testCheckConcurrentMap(t, m)
+			if v := m.Len(); v != len(expected) {
+				t.Fatalf("Expected len = %d, got %d", len(expected), v)
+			}
+			for key := 0; key < 1000; key++ {
+				value, ok := m.Get(key)
+				if v, ok2 := expected[key]; ok != ok2 || value != v {
+					t.Fatalf("Expected value = %d, got %d", v, value)
+				}
+			}
+			var keys []int
+			for key, value := range m.All() {
+				if value != expected[key] {
+					t.Fatalf("Expected value = %d, got %d", expected[key], value)
+				}
+				keys = append(keys, key)
+			}
+			if len(keys) != len(expected) || !slices.IsSorted(keys) {
+				t.Fatal("Invalid order of keys")
+			}
+		})
+	}
+}
+
+func TestConcurrentMapRange(t *testing.T) {
+	m := NewConcurrentMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	for i := 0; i < 100; i++ {
+		m.Set(i*2, i)
+	}
+	for lo := -1; lo < 201; lo++ {
+		for _, hi := range []int{lo, lo + 1, lo + 7, 300} {
+			var keys []int
+			for key := range m.Range(lo, hi) {
+				keys = append(keys, key)
+			}
+			var expected []int
+			for key := max(lo+lo%2, 0); key < min(hi, 200); key += 2 {
+				expected = append(expected, key)
+			}
+			if !slices.Equal(keys, expected) {
+				t.Fatalf("Expected %v, got %v", expected, keys)
+			}
+		}
+	}
+	count := 0
+	for key := range m.RangeFrom(150) {
+		if key < 150 {
+			t.Fatalf("Key %d is out of range", key)
+		}
+		count++
+	}
+	if count != 25 {
+		t.Fatalf("Expected %d items, got %d", 25, count)
+	}
+	for range m.All() {
+		break
+	}
+}
+
+func TestConcurrentMapStress(t *testing.T) {
+	m := NewConcurrentMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	workers, n := 8, 2000
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(int64(w)))
+			// Each worker owns keys with the same remainder, so final
+			// state of map is known.
+			for _, i := range rnd.Perm(n) {
+				key := i*workers + w
+				m.Set(key, key)
+				if i%3 == 0 {
+					m.Delete(key)
+				}
+				if value, ok := m.Get(key); ok != (i%3 != 0) || (ok && value != key) {
+					t.Errorf("Unexpected value of key %d", key)
+					return
+				}
+			}
+		}(w)
+	}
+	var done atomic.Bool
+	var scanners sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		scanners.Add(1)
+		go func() {
+			defer scanners.Done()
+			for !done.Load() {
+				last := -1
+				for key, value := range m.RangeFrom(w * n) {
+					if key <= last || key != value {
+						t.Errorf("Key %d is out of order", key)
+						return
+					}
+					last = key
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	done.Store(true)
+	scanners.Wait()
+	testCheckConcurrentMap(t, m)
+	count := 0
+	for key := 0; key < n*workers; key++ {
+		_, ok := m.Get(key)
+		if ok != (key/workers%3 != 0) {
+			t.Fatalf("Unexpected value of key %d", key)
+		}
+		if ok {
+			count++
+		}
+	}
+	if v := m.Len(); v != count {
+		t.Fatalf("Expected len = %d, got %d", count, v)
+	}
+}
+
+// linOp represents operation of history with logical time of call and
+// return.
+type linOp struct {
+	// kind is one of 'g' (get), 's' (set) and 'd' (delete).
+	kind  byte
+	key   int
+	value int
+	ok    bool
+	call  int64
+	ret   int64
+}
+
+// linState represents state of single key in sequential model.
+type linState struct {
+	value int
+	ok    bool
+}
+
+// apply applies operation to state and returns false if result of
+// operation is not possible in this state.
+func (op linOp) apply(s linState) (linState, bool) {
+	switch op.kind {
+	case 's':
+		return linState{op.value, true}, true
+	case 'd':
+		return linState{}, true
+	default:
+		return s, s.ok == op.ok && (!op.ok || s.value == op.value)
+	}
+}
+
+// testLinearizable returns true if history of operations on single key
+// is linearizable with respect to sequential model of map.
+//
+// Operations on different keys are independent, so history of map
+// is linearizable if histories of all keys are linearizable.
+func testLinearizable(ops []linOp) bool {
+	done := make([]bool, len(ops))
+	seen := map[string]bool{}
+	var search func(s linState, left int) bool
+	search = func(s linState, left int) bool {
+		if left == 0 {
+			return true
+		}
+		key := fmt.Sprint(done, s)
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+		// Operation can be linearized first only if it is called before
+		// return of all remaining operations.
+		minRet := int64(-1)
+		for i, op := range ops {
+			if !done[i] && (minRet < 0 || op.ret < minRet) {
+				minRet = op.ret
+			}
+		}
+		for i, op := range ops {
+			if done[i] || op.call > minRet {
+				continue
+			}
+			next, ok := op.apply(s)
+			if !ok {
+				continue
+			}
+			done[i] = true
+			if search(next, left-1) {
+				return true
+			}
+			done[i] = false
+		}
+		return false
+	}
+	return search(linState{}, len(ops))
+}
+
+func TestLinearizable(t *testing.T) {
+	ops := []linOp{
+		{kind: 's', value: 1, call: 1, ret: 4},
+		{kind: 'g', value: 1, ok: true, call: 2, ret: 3},
+		{kind: 'g', call: 5, ret: 6},
+	}
+	if testLinearizable(ops) {
+		t.Fatal("History should not be linearizable")
+	}
+	ops[2] = linOp{kind: 'g', value: 1, ok: true, call: 5, ret: 8}
+	ops = append(ops, linOp{kind: 'd', call: 6, ret: 7})
+	if !testLinearizable(ops) {
+		t.Fatal("History should be linearizable")
+	}
+}
+
+func TestConcurrentMapLinearizable(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		m := NewConcurrentMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+		var clock atomic.Int64
+		workers, keys := 6, 32
+		histories := make([][]linOp, workers)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				rnd := rand.New(rand.NewSource(int64(round*workers + w)))
+				for i := 0; i < 100; i++ {
+					op := linOp{key: rnd.Intn(keys), value: w*1000 + i}
+					op.call = clock.Add(1)
+					switch rnd.Intn(3) {
+					case 0:
+						op.kind = 's'
+						m.Set(op.key, op.value)
+					case 1:
+						op.kind = 'd'
+						m.Delete(op.key)
+					default:
+						op.kind = 'g'
+						op.value, op.ok = m.Get(op.key)
+					}
+					op.ret = clock.Add(1)
+					histories[w] = append(histories[w], op)
+				}
+			}(w)
+		}
+		wg.Wait()
+		byKey := make([][]linOp, keys)
+		for _, history := range histories {
+			for _, op := range history {
+				byKey[op.key] = append(byKey[op.key], op)
+			}
+		}
+		for key, ops := range byKey {
+			if !testLinearizable(ops) {
+				t.Fatalf("History of key %d is not linearizable", key)
+			}
+		}
+		testCheckConcurrentMap(t, m)
+	}
+}
+
+func BenchmarkBtreeConcurrentMapParallelSet(b *testing.B) {
+	m := NewConcurrentMap[int, int](intLess)
+	var seed atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		rnd := rand.New(rand.NewSource(seed.Add(1)))
+		for pb.Next() {
+			key := rnd.Intn(1 << 20)
+			m.Set(key, key)
+		}
+	})
+}
+
+func BenchmarkBtreeRWMutexMapParallelSet(b *testing.B) {
+	m := NewMap[int, int](intLess)
+	var mutex sync.RWMutex
+	var seed atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		rnd := rand.New(rand.NewSource(seed.Add(1)))
+		for pb.Next() {
+			key := rnd.Intn(1 << 20)
+			mutex.Lock()
+			m.Set(key, key)
+			mutex.Unlock()
+		}
+	})
+}
+
+func BenchmarkBtreeConcurrentMapParallelGet(b *testing.B) {
+	m := NewConcurrentMap[int, int](intLess)
+	for i := 0; i < 1<<16; i++ {
+		m.Set(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, ok := m.Get(i & (1<<16 - 1)); !ok {
+				b.Errorf("Unable to find key = %d", i)
+				return
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,663 @@
+package btree
+
+import (
+	"errors"
+	"iter"
+	"os"
+	"slices"
+)
+
+// ErrClosed means that DiskMap is closed.
+var ErrClosed = errors.New("map is closed")
+
+// DiskMap represents map implementation using B-Tree stored in file.
+//
+// Nodes are stored in pages of fixed size and only recently used
+// pages are cached in memory. Nodes are split and merged by encoded
+// size of items instead of amount of items.
+//
+// Changes are written to file atomically by Sync, so after crash file
+// contains state of last successful Sync. Methods of map do not return
+// I/O errors: the first error is remembered, after that modifications
+// are ignored and the error is returned by Err, Sync and Close.
+type DiskMap[K, V any] interface {
+	Get(key K) (V, bool)
+	// Set sets value of key.
+	//
+	// Set panics if encoded item is larger than quarter of page.
+	Set(key K, value V)
+	Delete(key K)
+	Len() int
+	Iter() MapIter[K, V]
+	// All returns iterator over all items in ascending order.
+	//
+	// Map can be modified during iteration: after each modification
+	// iteration continues from the first item with greater key.
+	All() iter.Seq2[K, V]
+	// Sync writes all changes to file.
+	Sync() error
+	// Err returns the first I/O error.
+	Err() error
+	// Close syncs and closes file of map.
+	Close() error
+}
+
+// DiskMapOptions represents options of DiskMap.
+type DiskMapOptions[K, V any] struct {
+	// KeyCodec represents encoding of keys.
+	KeyCodec Codec[K]
+	// ValueCodec represents encoding of values.
+	ValueCodec Codec[V]
+	// PageSize represents size of page in bytes. PageSize is used
+	// only when new file is created, otherwise size of page is read
+	// from file.
+	//
+	// PageSize should be at least 1024, default is 4096.
+	PageSize int
+	// CacheSize represents maximal amount of pages that are cached
+	// between operations, default is 256.
+	CacheSize int
+}
+
+// OpenDiskMap opens map stored in file or creates new empty map.
+func OpenDiskMap[K, V any](
+	path string, less func(K, K) bool, options DiskMapOptions[K, V],
+) (DiskMap[K, V], error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPager(file, options)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &diskMapImpl[K, V]{
+		pager:  p,
+		less:   less,
+		root:   p.header.root,
+		height: p.header.height,
+		len:    p.header.len,
+	}, nil
+}
+
+type diskMapImpl[K, V any] struct {
+	pager *pager[K, V]
+	less  func(K, K) bool
+	// root is page of root node or zero if map is empty.
+	root   uint64
+	height int
+	len    int
+	// version is changed on each modification of map.
+	version uint64
+	err     error
+}
+
+func (m *diskMapImpl[K, V]) Get(key K) (value V, ok bool) {
+	if m.err != nil || m.root == 0 {
+		return
+	}
+	defer m.catch()
+	defer m.pager.trim()
+	id := m.root
+	for {
+		n := m.pager.node(id)
+		i, found := m.search(n, key)
+		if found {
+			return n.values[i], true
+		}
+		if n.children == nil {
+			return
+		}
+		id = n.children[i]
+	}
+}
+
+func (m *diskMapImpl[K, V]) Set(key K, value V) {
+	if m.err != nil {
+		return
+	}
+	size := m.checkItem(key, value)
+	defer m.catch()
+	defer m.pager.trim()
+	m.version++
+	if m.root == 0 {
+		m.root = m.newRoot(nil)
+	}
+	if m.setNode(&m.root, key, value, size) {
+		m.len++
+	}
+	if root := m.pager.node(m.root); m.overflow(root) {
+		m.root = m.newRoot([]uint64{m.root})
+		m.splitChild(m.pager.node(m.root), 0)
+	}
+}
+
+func (m *diskMapImpl[K, V]) Delete(key K) {
+	if m.err != nil || m.root == 0 {
+		return
+	}
+	defer m.catch()
+	defer m.pager.trim()
+	if _, ok := m.Get(key); !ok {
+		return
+	}
+	m.version++
+	m.deleteNode(&m.root, key)
+	m.len--
+	root := m.pager.node(m.root)
+	if len(root.keys) > 0 {
+		return
+	}
+	m.pager.release(root)
+	m.height--
+	if root.children != nil {
+		m.root = root.children[0]
+	} else {
+		m.root = 0
+	}
+}
+
+func (m *diskMapImpl[K, V]) Len() int {
+	return m.len
+}
+
+func (m *diskMapImpl[K, V]) Iter() MapIter[K, V] {
+	return &diskIter[K, V]{m: m}
+}
+
+func (m *diskMapImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := diskIter[K, V]{m: m}
+		walk(&it, it.First(), false, m.less, &m.version, yield)
+	}
+}
+
+func (m *diskMapImpl[K, V]) Sync() error {
+	if m.err != nil {
+		return m.err
+	}
+	m.commit()
+	return m.err
+}
+
+func (m *diskMapImpl[K, V]) Err() error {
+	return m.err
+}
+
+func (m *diskMapImpl[K, V]) Close() error {
+	if m.pager.file == nil {
+		return ErrClosed
+	}
+	err := m.Sync()
+	if closeErr := m.pager.file.Close(); err == nil {
+		err = closeErr
+	}
+	m.pager.file = nil
+	if m.err == nil {
+		m.err = ErrClosed
+	}
+	return err
+}
+
+// commit writes all changes to file and remembers I/O error.
+func (m *diskMapImpl[K, V]) commit() {
+	defer m.catch()
+	m.pager.commit(m.root, m.height, m.len)
+	m.pager.trim()
+}
+
+// catch remembers I/O error that is passed by pager through panic.
+func (m *diskMapImpl[K, V]) catch() {
+	if r := recover(); r != nil {
+		m.fail(r)
+	}
+}
+
+// fail remembers I/O error or continues panic if it is not caused
+// by I/O error.
+func (m *diskMapImpl[K, V]) fail(r any) {
+	e, ok := r.(diskError)
+	if !ok {
+		panic(r)
+	}
+	m.err = e.err
+}
+
+func (m *diskMapImpl[K, V]) search(n *diskNode[K, V], key K) (int, bool) {
+	low, high := 0, len(n.keys)
+	for low < high {
+		mid := (low + high) / 2
+		if m.less(key, n.keys[mid]) {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	if low > 0 && !m.less(n.keys[low-1], key) {
+		return low - 1, true
+	}
+	return low, false
+}
+
+// checkItem returns encoded size of item or panics if item is too
+// large for page.
+func (m *diskMapImpl[K, V]) checkItem(key K, value V) int {
+	size := m.pager.itemSize(key, value)
+	if size+8 > m.pager.capacity()/4 {
+		panic("item is too large")
+	}
+	return size
+}
+
+// newRoot creates new root with specified children and returns
+// its page.
+func (m *diskMapImpl[K, V]) newRoot(children []uint64) uint64 {
+	n := diskNode[K, V]{children: children}
+	m.pager.alloc(&n)
+	m.height++
+	return n.id
+}
+
+// setNode sets value of key in subtree and returns true if new item
+// is added.
+//
+// After insertion root of subtree can overflow, so parent should split
+// it using splitChild.
+func (m *diskMapImpl[K, V]) setNode(ref *uint64, key K, value V, size int) bool {
+	n := m.pager.mutable(ref)
+	i, ok := m.search(n, key)
+	if ok {
+		n.values[i] = value
+		n.sizes[i] = size
+		return false
+	}
+	if n.children == nil {
+		n.keys = slices.Insert(n.keys, i, key)
+		n.values = slices.Insert(n.values, i, value)
+		n.sizes = slices.Insert(n.sizes, i, size)
+		return true
+	}
+	added := m.setNode(&n.children[i], key, value, size)
+	if m.overflow(m.pager.node(n.children[i])) {
+		m.splitChild(n, i)
+	}
+	return added
+}
+
+// deleteNode deletes existing key from subtree.
+//
+// After deletion root of subtree can underflow, so parent should
+// rebalance it using rebalanceChild.
+func (m *diskMapImpl[K, V]) deleteNode(ref *uint64, key K) {
+	n := m.pager.mutable(ref)
+	i, ok := m.search(n, key)
+	if n.children == nil {
+		m.removeItem(n, i)
+		return
+	}
+	if ok {
+		// Item is replaced with the largest item of left subtree.
+		n.keys[i], n.values[i], n.sizes[i] = m.deleteMaxNode(&n.children[i])
+	} else {
+		m.deleteNode(&n.children[i], key)
+	}
+	if m.underflow(m.pager.node(n.children[i])) {
+		m.rebalanceChild(n, i)
+	}
+}
+
+// deleteMaxNode deletes and returns item with the largest key
+// of subtree.
+func (m *diskMapImpl[K, V]) deleteMaxNode(ref *uint64) (K, V, int) {
+	n := m.pager.mutable(ref)
+	i := len(n.keys) - 1
+	if n.children == nil {
+		key, value, size := n.keys[i], n.values[i], n.sizes[i]
+		m.removeItem(n, i)
+		return key, value, size
+	}
+	key, value, size := m.deleteMaxNode(&n.children[i+1])
+	if m.underflow(m.pager.node(n.children[i+1])) {
+		m.rebalanceChild(n, i+1)
+	}
+	return key, value, size
+}
+
+func (m *diskMapImpl[K, V]) removeItem(n *diskNode[K, V], i int) {
+	n.keys = slices.Delete(n.keys, i, i+1)
+	n.values = slices.Delete(n.values, i, i+1)
+	n.sizes = slices.Delete(n.sizes, i, i+1)
+}
+
+// nodeSize returns encoded size of items and references to children.
+func (m *diskMapImpl[K, V]) nodeSize(n *diskNode[K, V]) int {
+	size := 8 * len(n.children)
+	for _, s := range n.sizes {
+		size += s
+	}
+	return size
+}
+
+// overflow returns true if node does not fit into page.
+func (m *diskMapImpl[K, V]) overflow(n *diskNode[K, V]) bool {
+	return m.nodeSize(n) > m.pager.capacity()
+}
+
+// underflow returns true if node uses less than quarter of page.
+func (m *diskMapImpl[K, V]) underflow(n *diskNode[K, V]) bool {
+	return m.nodeSize(n) < m.pager.capacity()/4
+}
+
+// splitChild splits child i into two nodes with equal sizes.
+func (m *diskMapImpl[K, V]) splitChild(n *diskNode[K, V], i int) {
+	left := m.pager.mutable(&n.children[i])
+	right := diskNode[K, V]{}
+	key, value, size := m.splitItems(left, &right)
+	m.pager.alloc(&right)
+	n.keys = slices.Insert(n.keys, i, key)
+	n.values = slices.Insert(n.values, i, value)
+	n.sizes = slices.Insert(n.sizes, i, size)
+	n.children = slices.Insert(n.children, i+1, right.id)
+}
+
+// splitItems moves the second half of items of left node to right
+// node and returns middle item.
+//
+// Halves are chosen by encoded size, so with items not larger than
+// quarter of page both halves fit into page.
+func (m *diskMapImpl[K, V]) splitItems(left, right *diskNode[K, V]) (K, V, int) {
+	extra := 0
+	if left.children != nil {
+		extra = 8
+	}
+	total := m.nodeSize(left)
+	k, size := 0, extra
+	for k+1 < len(left.keys)-1 && 2*(size+left.sizes[k]+extra) <= total {
+		size += left.sizes[k] + extra
+		k++
+	}
+	key, value, itemSize := left.keys[k], left.values[k], left.sizes[k]
+	right.keys = append(right.keys, left.keys[k+1:]...)
+	right.values = append(right.values, left.values[k+1:]...)
+	right.sizes = append(right.sizes, left.sizes[k+1:]...)
+	if left.children != nil {
+		right.children = append(right.children, left.children[k+1:]...)
+		clear(left.children[k+1:])
+		left.children = left.children[:k+1]
+	}
+	clear(left.keys[k:])
+	clear(left.values[k:])
+	left.keys = left.keys[:k]
+	left.values = left.values[:k]
+	left.sizes = left.sizes[:k]
+	return key, value, itemSize
+}
+
+// rebalanceChild merges underflowed child i with its sibling or evenly
+// redistributes their items.
+func (m *diskMapImpl[K, V]) rebalanceChild(n *diskNode[K, V], i int) {
+	if len(n.keys) == 0 {
+		return
+	}
+	if i == len(n.keys) {
+		i--
+	}
+	left := m.pager.mutable(&n.children[i])
+	right := m.pager.mutable(&n.children[i+1])
+	left.keys = append(append(left.keys, n.keys[i]), right.keys...)
+	left.values = append(append(left.values, n.values[i]), right.values...)
+	left.sizes = append(append(left.sizes, n.sizes[i]), right.sizes...)
+	if left.children != nil {
+		left.children = append(left.children, right.children...)
+	}
+	if !m.overflow(left) {
+		m.pager.release(right)
+		m.removeItem(n, i)
+		n.children = slices.Delete(n.children, i+1, i+2)
+		return
+	}
+	right.keys = right.keys[:0]
+	right.values = right.values[:0]
+	right.sizes = right.sizes[:0]
+	if right.children != nil {
+		right.children = right.children[:0]
+	}
+	n.keys[i], n.values[i], n.sizes[i] = m.splitItems(left, right)
+}
+
+// diskIter represents iterator over DiskMap.
+//
+// Iterator keeps path from root to current item. Nodes of path stay
+// valid while map is not modified even if they are evicted from cache.
+type diskIter[K, V any] struct {
+	m      *diskMapImpl[K, V]
+	stack  []diskIterPos[K, V]
+	seeked bool
+	// version is version of map at the moment of positioning.
+	version uint64
+}
+
+type diskIterPos[K, V any] struct {
+	n *diskNode[K, V]
+	i int
+}
+
+func (it *diskIter[K, V]) Next() bool {
+	if !it.seeked {
+		return it.First()
+	}
+	it.check()
+	if it.m.err != nil {
+		return it.reset()
+	}
+	defer it.catch()
+	defer it.m.pager.trim()
+	s := &it.stack[len(it.stack)-1]
+	s.i++
+	if s.n.children == nil {
+		for s.i == len(s.n.keys) {
+			it.stack = it.stack[:len(it.stack)-1]
+			if len(it.stack) == 0 {
+				return it.reset()
+			}
+			s = &it.stack[len(it.stack)-1]
+		}
+		return true
+	}
+	it.descend(s.n.children[s.i], false)
+	return true
+}
+
+func (it *diskIter[K, V]) Prev() bool {
+	if !it.seeked {
+		return it.Last()
+	}
+	it.check()
+	if it.m.err != nil {
+		return it.reset()
+	}
+	defer it.catch()
+	defer it.m.pager.trim()
+	s := &it.stack[len(it.stack)-1]
+	if s.n.children == nil {
+		s.i--
+		for s.i < 0 {
+			it.stack = it.stack[:len(it.stack)-1]
+			if len(it.stack) == 0 {
+				return it.reset()
+			}
+			s = &it.stack[len(it.stack)-1]
+			s.i--
+		}
+		return true
+	}
+	it.descend(s.n.children[s.i], true)
+	return true
+}
+
+func (it *diskIter[K, V]) First() bool {
+	if !it.position() {
+		return false
+	}
+	defer it.catch()
+	defer it.m.pager.trim()
+	it.descend(it.m.root, false)
+	return true
+}
+
+func (it *diskIter[K, V]) Last() bool {
+	if !it.position() {
+		return false
+	}
+	defer it.catch()
+	defer it.m.pager.trim()
+	it.descend(it.m.root, true)
+	return true
+}
+
+func (it *diskIter[K, V]) Seek(key K) bool {
+	if !it.position() {
+		return false
+	}
+	defer it.catch()
+	defer it.m.pager.trim()
+	id := it.m.root
+	for {
+		n := it.m.pager.node(id)
+		i, ok := it.m.search(n, key)
+		it.stack = append(it.stack, diskIterPos[K, V]{n, i})
+		if ok {
+			return true
+		}
+		if n.children == nil {
+			it.stack[len(it.stack)-1].i--
+			return it.Next()
+		}
+		id = n.children[i]
+	}
+}
+
+func (it *diskIter[K, V]) SeekPrev(key K) bool {
+	if !it.position() {
+		return false
+	}
+	defer it.catch()
+	defer it.m.pager.trim()
+	id := it.m.root
+	for {
+		n := it.m.pager.node(id)
+		i, ok := it.m.search(n, key)
+		it.stack = append(it.stack, diskIterPos[K, V]{n, i})
+		if ok {
+			return true
+		}
+		if n.children == nil {
+			return it.Prev()
+		}
+		id = n.children[i]
+	}
+}
+
+func (it *diskIter[K, V]) Key() K {
+	if !it.seeked {
+		var empty K
+		return empty
+	}
+	it.check()
+	s := it.stack[len(it.stack)-1]
+	return s.n.keys[s.i]
+}
+
+func (it *diskIter[K, V]) Value() V {
+	if !it.seeked {
+		var empty V
+		return empty
+	}
+	it.check()
+	s := it.stack[len(it.stack)-1]
+	return s.n.values[s.i]
+}
+
+// SetValue sets value of current item.
+//
+// Value is set by key, because size of new value can differ.
+func (it *diskIter[K, V]) SetValue(value V) {
+	it.checkPositioned()
+	key := it.Key()
+	it.m.Set(key, value)
+	if !it.Seek(key) {
+		it.reset()
+	}
+}
+
+func (it *diskIter[K, V]) Delete() bool {
+	if !it.seeked {
+		return false
+	}
+	key := it.Key()
+	it.m.Delete(key)
+	// Key is already removed, so seek moves to the next item.
+	if !it.Seek(key) {
+		return it.reset()
+	}
+	return true
+}
+
+// position prepares iterator for positioning from root.
+func (it *diskIter[K, V]) position() bool {
+	if it.m.err != nil || it.m.root == 0 {
+		return it.reset()
+	}
+	it.seeked = true
+	it.version = it.m.version
+	it.stack = it.stack[:0]
+	return true
+}
+
+// descend moves iterator to the smallest or the largest item
+// of subtree.
+func (it *diskIter[K, V]) descend(id uint64, last bool) {
+	for {
+		n := it.m.pager.node(id)
+		i := 0
+		if last {
+			i = len(n.keys)
+		}
+		it.stack = append(it.stack, diskIterPos[K, V]{n, i})
+		if n.children == nil {
+			if last {
+				it.stack[len(it.stack)-1].i--
+			}
+			return
+		}
+		id = n.children[i]
+	}
+}
+
+// catch remembers I/O error and makes iterator unpositioned.
+func (it *diskIter[K, V]) catch() {
+	if r := recover(); r != nil {
+		it.m.fail(r)
+		it.reset()
+	}
+}
+
+// reset makes iterator unpositioned and returns false.
+func (it *diskIter[K, V]) reset() bool {
+	it.seeked = false
+	it.stack = it.stack[:0]
+	return false
+}
+
+// checkPositioned panics if iterator is not positioned.
+func (it *diskIter[K, V]) checkPositioned() {
+	if !it.seeked {
+		panic("iterator is not positioned")
+	}
+}
+
+// check panics if map was modified after positioning of iterator.
+func (it *diskIter[K, V]) check() {
+	checkVersion(it.version, it.m.version)
+}
@@ -0,0 +1,434 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/udovin/algo/ordered"
+	"github.com/udovin/algo/ordered/orderedtest"
+)
+
+var testDiskOptions = DiskMapOptions[int, int]{
+	KeyCodec:   IntCodec{},
+	ValueCodec: IntCodec{},
+	PageSize:   1024,
+	CacheSize:  16,
+}
+
+func openTestDiskMap(tb testing.TB, path string) DiskMap[int, int] {
+	m, err := OpenDiskMap(path, intLess, testDiskOptions)
+	if err != nil {
+		tb.Fatal("Error:", err)
+	}
+	return m
+}
+
+func testCheckDiskMap(tb testing.TB, m DiskMap[int, int]) {
+	impl := m.(*diskMapImpl[int, int])
+	if err := m.Err(); err != nil {
+		tb.Fatal("Error:", err)
+	}
+	if impl.root == 0 {
+		if impl.len != 0 || impl.height != 0 {
+			tb.Fatalf("Expected len = %d, got %d", 0, impl.len)
+		}
+		return
+	}
+	count := 0
+	var check func(id uint64, level int, lo, hi *int)
+	check = func(id uint64, level int, lo, hi *int) {
+		n := impl.pager.node(id)
+		if impl.overflow(n) || (id != impl.root && impl.underflow(n)) {
+			tb.Fatalf("Invalid node size = %d", impl.nodeSize(n))
+		}
+		for i, key := range n.keys {
+			if (i > 0 && n.keys[i-1] >= key) ||
+				(lo != nil && key <= *lo) ||
+				(hi != nil && key >= *hi) {
+				tb.Fatalf("Key %d is out of order", key)
+			}
+			if size := impl.pager.itemSize(key, n.values[i]); size != n.sizes[i] {
+				tb.Fatalf("Expected size = %d, got %d", size, n.sizes[i])
+			}
+		}
+		count += len(n.keys)
+		if n.children == nil {
+			if level != impl.height {
+				tb.Fatal("Tree is not balanced")
+			}
+			return
+		}
+		if len(n.children) != len(n.keys)+1 {
+			tb.Fatalf("Invalid amount of children = %d", len(n.children))
+		}
+		for i, child := range n.children {
+			clo, chi := lo, hi
+			if i > 0 {
+				clo = &n.keys[i-1]
+			}
+			if i < len(n.keys) {
+				chi = &n.keys[i]
+			}
+			check(child, level+1, clo, chi)
+		}
+	}
+	check(impl.root, 1, nil, nil)
+	if count != impl.len {
+		tb.Fatalf("Expected len = %d, got %d", count, impl.len)
+	}
+}
+
+func TestOrderedDiskMap(t *testing.T) {
+	dir := t.TempDir()
+	index := 0
+	orderedtest.TestMap(t, func() ordered.Map[int, int] {
+		index++
+		m := openTestDiskMap(t, filepath.Join(dir, fmt.Sprint(index)))
+		t.Cleanup(func() { _ = m.Close() })
+		return AsOrdered(m)
+	})
+}
+
+func TestDiskMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m := openTestDiskMap(t, path)
+	rnd := rand.New(rand.NewSource(42))
+	expected := map[int]int{}
+	for i := 0; i < 20000; i++ {
+		key := rnd.Intn(5000)
+		if rnd.Intn(3) == 0 {
+			m.Delete(key)
+			delete(expected, key)
+		} else {
+			m.Set(key, i)
+			expected[key] = i
+		}
+		if i%1000 == 0 {
+			testCheckDiskMap(t, m)
+		}
+		if i%3000 == 0 {
+			if err := m.Sync(); err != nil {
+				t.Fatal("Error:", err)
+			}
+		}
+	}
+	testCheckDiskMap(t, m)
+	if err := m.Close(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	m = openTestDiskMap(t, path)
+	defer func() { _ = m.Close() }()
+	testCheckDiskMap(t, m)
+	if v := m.Len(); v != len(expected) {
+		t.Fatalf("Expected len = %d, got %d", len(expected), v)
+	}
+	for key, value := range expected {
+		if v, ok := m.Get(key); !ok || v != value {
+			t.Fatalf("Expected value = %d, got %d", value, v)
+		}
+	}
+	var keys []int
+	for key := range m.All() {
+		keys = append(keys, key)
+	}
+	if len(keys) != len(expected) || !slices.IsSorted(keys) {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+}
+
+func TestDiskMapStrings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	options := DiskMapOptions[string, string]{
+		KeyCodec:   StringCodec{},
+		ValueCodec: StringCodec{},
+		PageSize:   1024,
+		CacheSize:  4,
+	}
+	m, err := OpenDiskMap(path, func(x, y string) bool { return x < y }, options)
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	rnd := rand.New(rand.NewSource(42))
+	expected := map[string]struct{}{}
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("tenant/%d/%s", rnd.Intn(100), strings.Repeat("x", rnd.Intn(100)))
+		m.Set(key, strings.Repeat("v", rnd.Intn(100)))
+		expected[key] = struct{}{}
+		if rnd.Intn(2) == 0 {
+			m.Delete(key)
+			delete(expected, key)
+		}
+	}
+	keys := slices.Sorted(maps.Keys(expected))
+	if err := m.Close(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	m, err = OpenDiskMap(path, func(x, y string) bool { return x < y }, options)
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	defer func() { _ = m.Close() }()
+	var result []string
+	for key := range m.All() {
+		result = append(result, key)
+	}
+	if !slices.Equal(result, keys) {
+		t.Fatalf("Expected %d keys, got %d", len(keys), len(result))
+	}
+}
+
+func TestDiskMapCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m := openTestDiskMap(t, path)
+	n := 10000
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	if err := m.Sync(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	for i := 0; i < n; i++ {
+		m.Set(i, -i)
+		m.Set(i+n, i)
+	}
+	for i := 0; i < n/2; i++ {
+		m.Delete(i)
+	}
+	// Changes after Sync are partially written to file, because cache
+	// is small. Map is abandoned without Sync.
+	_ = m.(*diskMapImpl[int, int]).pager.file.Close()
+	m = openTestDiskMap(t, path)
+	defer func() { _ = m.Close() }()
+	testCheckDiskMap(t, m)
+	if v := m.Len(); v != n {
+		t.Fatalf("Expected len = %d, got %d", n, v)
+	}
+	for i := 0; i < n; i++ {
+		if v, ok := m.Get(i); !ok || v != i {
+			t.Fatalf("Expected value = %d, got %d", i, v)
+		}
+	}
+}
+
+func TestDiskMapReuse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m := openTestDiskMap(t, path)
+	defer func() { _ = m.Close() }()
+	for i := 0; i < 1000; i++ {
+		m.Set(i, i)
+	}
+	if err := m.Sync(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	pages := m.(*diskMapImpl[int, int]).pager.pages
+	for j := 0; j < 10; j++ {
+		for i := 0; i < 1000; i++ {
+			m.Set(i, i+j)
+		}
+		if err := m.Sync(); err != nil {
+			t.Fatal("Error:", err)
+		}
+	}
+	// Each commit copies modified nodes, so file grows at most twice.
+	if v := m.(*diskMapImpl[int, int]).pager.pages; v > 2*pages+1 {
+		t.Fatalf("Expected at most %d pages, got %d", 2*pages+1, v)
+	}
+	testCheckDiskMap(t, m)
+}
+
+func TestDiskMapIter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m := openTestDiskMap(t, path)
+	defer func() { _ = m.Close() }()
+	for i := 0; i < 1000; i++ {
+		m.Set(i, i)
+	}
+	it := m.Iter()
+	for ok := it.Seek(100); ok && it.Key() < 200; {
+		ok = it.Delete()
+	}
+	if v := it.Key(); v != 200 {
+		t.Fatalf("Expected key = %d, got %d", 200, v)
+	}
+	if v := m.Len(); v != 900 {
+		t.Fatalf("Expected len = %d, got %d", 900, v)
+	}
+	if !it.SeekPrev(150) || it.Key() != 99 {
+		t.Fatal("Invalid item before removed range")
+	}
+	it.SetValue(-1)
+	if v, _ := m.Get(99); v != -1 {
+		t.Fatalf("Expected value = %d, got %d", -1, v)
+	}
+	if !it.Next() || it.Key() != 200 {
+		t.Fatal("Invalid item after removed range")
+	}
+	m.Set(1000, 1000)
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("Expected panic")
+			}
+		}()
+		it.Next()
+	}()
+	testCheckDiskMap(t, m)
+}
+
+func TestDiskMapIterDeleteUnpositioned(t *testing.T) {
+	m := openTestDiskMap(t, filepath.Join(t.TempDir(), "map"))
+	defer func() { _ = m.Close() }()
+	m.Set(0, 0)
+	m.Set(1, 1)
+	it := m.Iter()
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	for ok := it.First(); ok; ok = it.Next() {
+	}
+	if it.Key() != 0 || it.Value() != 0 {
+		t.Fatal("Unpositioned iterator should return empty item")
+	}
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	if v := m.Len(); v != 2 {
+		t.Fatalf("Expected len = %d, got %d", 2, v)
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Expected panic")
+		}
+		if _, ok := m.Get(0); !ok || m.Len() != 2 {
+			t.Fatal("Map should not be modified")
+		}
+	}()
+	it.SetValue(1)
+}
+
+func TestDiskMapCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m := openTestDiskMap(t, path)
+	for i := 0; i < 1000; i++ {
+		m.Set(i, i)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	if err := m.Close(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected error %v, got %v", ErrClosed, err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	// Corrupt the last leaf.
+	stat, err := file.Stat()
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	if _, err := file.WriteAt([]byte{0xff}, stat.Size()-1); err != nil {
+		t.Fatal("Error:", err)
+	}
+	_ = file.Close()
+	m = openTestDiskMap(t, path)
+	defer func() { _ = m.Close() }()
+	for i := 0; i < 1000; i++ {
+		m.Get(i)
+	}
+	if err := m.Err(); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("Expected error %v, got %v", ErrCorrupted, err)
+	}
+	m.Set(0, 1)
+	if v, ok := m.Get(0); ok {
+		t.Fatalf("Unexpected value %d", v)
+	}
+}
+
+func TestDiskMapSyncError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m := openTestDiskMap(t, path)
+	m.Set(1, 1)
+	// File is closed under pager, so commit fails.
+	if err := m.(*diskMapImpl[int, int]).pager.file.Close(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	if err := m.Sync(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Expected error %v, got %v", os.ErrClosed, err)
+	}
+	if err := m.Err(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Expected error %v, got %v", os.ErrClosed, err)
+	}
+	if err := m.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Expected error %v, got %v", os.ErrClosed, err)
+	}
+}
+
+func TestDiskMapLargeItem(t *testing.T) {
+	m, err := OpenDiskMap(
+		filepath.Join(t.TempDir(), "map"),
+		func(x, y string) bool { return x < y },
+		DiskMapOptions[string, string]{
+			KeyCodec:   StringCodec{},
+			ValueCodec: StringCodec{},
+			PageSize:   1024,
+		},
+	)
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	defer func() { _ = m.Close() }()
+	m.Set("key", strings.Repeat("v", 200))
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Expected panic")
+		}
+	}()
+	m.Set("key", strings.Repeat("v", 300))
+}
+
+func BenchmarkBtreeDiskMapSet(b *testing.B) {
+	m, err := OpenDiskMap(filepath.Join(b.TempDir(), "map"), intLess, DiskMapOptions[int, int]{
+		KeyCodec:   IntCodec{},
+		ValueCodec: IntCodec{},
+	})
+	if err != nil {
+		b.Fatal("Error:", err)
+	}
+	defer func() { _ = m.Close() }()
+	rnd := rand.New(rand.NewSource(42))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Set(rnd.Int(), i)
+	}
+}
+
+func BenchmarkBtreeDiskMapGet(b *testing.B) {
+	m, err := OpenDiskMap(filepath.Join(b.TempDir(), "map"), intLess, DiskMapOptions[int, int]{
+		KeyCodec:   IntCodec{},
+		ValueCodec: IntCodec{},
+	})
+	if err != nil {
+		b.Fatal("Error:", err)
+	}
+	defer func() { _ = m.Close() }()
+	n := 1 << 16
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	if err := m.Sync(); err != nil {
+		b.Fatal("Error:", err)
+	}
+	rnd := rand.New(rand.NewSource(42))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Get(rnd.Intn(n))
+	}
+}
@@ -0,0 +1,282 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// DurableMap represents DiskMap with write-ahead log.
+//
+// Each modification is appended to log before it is applied to map.
+// Checkpoint writes all changes to file of map and truncates log, so
+// after crash map is recovered by replay of log over state of the
+// last checkpoint. Replay of log is idempotent, so crash between
+// writing of map and truncation of log is safe.
+type DurableMap[K, V any] interface {
+	Get(key K) (V, bool)
+	// Set sets value of key.
+	//
+	// Set panics if encoded item is larger than quarter of page.
+	Set(key K, value V)
+	Delete(key K)
+	Len() int
+	Iter() MapIter[K, V]
+	// All returns iterator over all items in ascending order.
+	//
+	// Map can be modified during iteration: after each modification
+	// iteration continues from the first item with greater key.
+	All() iter.Seq2[K, V]
+	// Sync syncs log, so all previous modifications become durable
+	// regardless of sync policy.
+	Sync() error
+	// Checkpoint writes all changes to file of map and truncates log.
+	Checkpoint() error
+	// Err returns the first I/O error.
+	Err() error
+	// Close makes checkpoint and closes files of map.
+	Close() error
+}
+
+// DurableMapOptions represents options of DurableMap.
+type DurableMapOptions[K, V any] struct {
+	DiskMapOptions[K, V]
+	// SyncPolicy represents policy of syncing log, default is
+	// SyncAlways.
+	SyncPolicy SyncPolicy
+	// BatchSize represents amount of records that are synced at once
+	// with SyncBatch policy, default is 64.
+	BatchSize int
+	// CheckpointSize represents size of log in bytes that triggers
+	// checkpoint, default is 16 MiB.
+	CheckpointSize int64
+}
+
+const (
+	defaultBatchSize      = 64
+	defaultCheckpointSize = 16 << 20
+)
+
+const (
+	setRecord    = 1
+	deleteRecord = 2
+)
+
+// OpenDurableMap opens map stored in file and replays its log.
+//
+// Log is stored in file with the same path and suffix ".wal".
+func OpenDurableMap[K, V any](
+	path string, less func(K, K) bool, options DurableMapOptions[K, V],
+) (DurableMap[K, V], error) {
+	if options.BatchSize == 0 {
+		options.BatchSize = defaultBatchSize
+	}
+	if options.CheckpointSize == 0 {
+		options.CheckpointSize = defaultCheckpointSize
+	}
+	tree, err := OpenDiskMap(path, less, options.DiskMapOptions)
+	if err != nil {
+		return nil, err
+	}
+	log, err := openWAL(path+".wal", options.SyncPolicy, options.BatchSize)
+	if err != nil {
+		_ = tree.Close()
+		return nil, err
+	}
+	m := durableMapImpl[K, V]{
+		tree:           tree.(*diskMapImpl[K, V]),
+		log:            log,
+		checkpointSize: options.CheckpointSize,
+	}
+	if err := log.replay(m.apply); err != nil {
+		_ = log.close()
+		_ = tree.Close()
+		return nil, err
+	}
+	if err := m.tree.Err(); err != nil {
+		_ = log.close()
+		_ = tree.Close()
+		return nil, err
+	}
+	return &m, nil
+}
+
+type durableMapImpl[K, V any] struct {
+	tree           *diskMapImpl[K, V]
+	log            *wal
+	checkpointSize int64
+	buf            []byte
+	err            error
+}
+
+func (m *durableMapImpl[K, V]) Get(key K) (V, bool) {
+	return m.tree.Get(key)
+}
+
+func (m *durableMapImpl[K, V]) Set(key K, value V) {
+	if m.err != nil {
+		return
+	}
+	if m.logSet(key, value) {
+		m.tree.Set(key, value)
+		m.applied()
+	}
+}
+
+func (m *durableMapImpl[K, V]) Delete(key K) {
+	if m.err != nil {
+		return
+	}
+	if _, ok := m.tree.Get(key); !ok {
+		return
+	}
+	if m.logDelete(key) {
+		m.tree.Delete(key)
+		m.applied()
+	}
+}
+
+func (m *durableMapImpl[K, V]) Len() int {
+	return m.tree.Len()
+}
+
+func (m *durableMapImpl[K, V]) Iter() MapIter[K, V] {
+	return &durableIter[K, V]{diskIter: &diskIter[K, V]{m: m.tree}, m: m}
+}
+
+func (m *durableMapImpl[K, V]) All() iter.Seq2[K, V] {
+	return m.tree.All()
+}
+
+func (m *durableMapImpl[K, V]) Sync() error {
+	if m.err != nil {
+		return m.err
+	}
+	if err := m.log.sync(); err != nil {
+		m.err = err
+	}
+	return m.err
+}
+
+func (m *durableMapImpl[K, V]) Checkpoint() error {
+	if m.err != nil {
+		return m.err
+	}
+	// Log is truncated only after all changes are written to file
+	// of map, otherwise they would be lost.
+	if err := errors.Join(m.tree.Sync(), m.tree.Err()); err != nil {
+		m.err = err
+		return err
+	}
+	if err := m.log.reset(); err != nil {
+		m.err = err
+	}
+	return m.err
+}
+
+func (m *durableMapImpl[K, V]) Err() error {
+	return m.err
+}
+
+func (m *durableMapImpl[K, V]) Close() error {
+	if errors.Is(m.err, ErrClosed) {
+		return ErrClosed
+	}
+	err := m.Checkpoint()
+	err = errors.Join(err, m.log.close(), m.tree.Close())
+	m.err = ErrClosed
+	return err
+}
+
+// logSet appends record of setting value to log and returns true
+// on success.
+func (m *durableMapImpl[K, V]) logSet(key K, value V) bool {
+	m.tree.checkItem(key, value)
+	m.buf = append(m.buf[:0], setRecord)
+	m.buf = m.tree.pager.keyCodec.Append(m.buf, key)
+	m.buf = m.tree.pager.valueCodec.Append(m.buf, value)
+	return m.write()
+}
+
+// logDelete appends record of deletion of key to log and returns true
+// on success.
+func (m *durableMapImpl[K, V]) logDelete(key K) bool {
+	m.buf = append(m.buf[:0], deleteRecord)
+	m.buf = m.tree.pager.keyCodec.Append(m.buf, key)
+	return m.write()
+}
+
+func (m *durableMapImpl[K, V]) write() bool {
+	if err := m.log.append(m.buf); err != nil {
+		m.err = err
+		return false
+	}
+	return true
+}
+
+// applied checks result of modification of map and makes checkpoint
+// if log is too large.
+func (m *durableMapImpl[K, V]) applied() {
+	if err := m.tree.Err(); err != nil {
+		m.err = err
+		return
+	}
+	if m.log.size >= m.checkpointSize {
+		_ = m.Checkpoint()
+	}
+}
+
+// apply applies record of log to map.
+func (m *durableMapImpl[K, V]) apply(payload []byte) error {
+	if len(payload) == 0 {
+		return fmt.Errorf("log: %w", ErrCorrupted)
+	}
+	key, n, err := m.tree.pager.keyCodec.Decode(payload[1:])
+	if err != nil {
+		return fmt.Errorf("log: %w", err)
+	}
+	switch payload[0] {
+	case setRecord:
+		value, _, err := m.tree.pager.valueCodec.Decode(payload[1+n:])
+		if err != nil {
+			return fmt.Errorf("log: %w", err)
+		}
+		m.tree.Set(key, value)
+	case deleteRecord:
+		m.tree.Delete(key)
+	default:
+		return fmt.Errorf("log: %w", ErrCorrupted)
+	}
+	return nil
+}
+
+// durableIter represents iterator that writes modifications to log.
+type durableIter[K, V any] struct {
+	*diskIter[K, V]
+	m *durableMapImpl[K, V]
+}
+
+func (it *durableIter[K, V]) SetValue(value V) {
+	it.checkPositioned()
+	m := it.m
+	if m.err != nil {
+		return
+	}
+	if m.logSet(it.Key(), value) {
+		it.diskIter.SetValue(value)
+		m.applied()
+	}
+}
+
+func (it *durableIter[K, V]) Delete() bool {
+	m := it.m
+	if m.err != nil || !it.seeked {
+		return false
+	}
+	if !m.logDelete(it.Key()) {
+		return false
+	}
+	ok := it.diskIter.Delete()
+	m.applied()
+	return ok
+}
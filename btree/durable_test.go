@@ -0,0 +1,228 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/udovin/algo/ordered"
+	"github.com/udovin/algo/ordered/orderedtest"
+)
+
+func openTestDurableMap(tb testing.TB, path string, policy SyncPolicy) DurableMap[int, int] {
+	m, err := OpenDurableMap(path, intLess, DurableMapOptions[int, int]{
+		DiskMapOptions: testDiskOptions,
+		SyncPolicy:     policy,
+	})
+	if err != nil {
+		tb.Fatal("Error:", err)
+	}
+	return m
+}
+
+// crashDurableMap closes files of map without checkpoint.
+func crashDurableMap(m DurableMap[int, int]) {
+	impl := m.(*durableMapImpl[int, int])
+	_ = impl.log.file.Close()
+	_ = impl.tree.pager.file.Close()
+}
+
+func testCheckDurableMap(tb testing.TB, m DurableMap[int, int], expected map[int]int) {
+	if v := m.Len(); v != len(expected) {
+		tb.Fatalf("Expected len = %d, got %d", len(expected), v)
+	}
+	count := 0
+	for key, value := range m.All() {
+		if v, ok := expected[key]; !ok || v != value {
+			tb.Fatalf("Invalid item (%d, %d)", key, value)
+		}
+		count++
+	}
+	if count != len(expected) {
+		tb.Fatalf("Expected %d items, got %d", len(expected), count)
+	}
+	testCheckDiskMap(tb, m.(*durableMapImpl[int, int]).tree)
+}
+
+func TestOrderedDurableMap(t *testing.T) {
+	dir := t.TempDir()
+	index := 0
+	orderedtest.TestMap(t, func() ordered.Map[int, int] {
+		index++
+		m := openTestDurableMap(t, filepath.Join(dir, fmt.Sprint(index)), SyncNone)
+		t.Cleanup(func() { _ = m.Close() })
+		return AsOrdered(m)
+	})
+}
+
+func TestDurableMapRecovery(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "map")
+	m := openTestDurableMap(t, path, SyncBatch)
+	rnd := rand.New(rand.NewSource(42))
+	// states contains expected state after each operation that is
+	// made after checkpoint and ends contains size of log after it.
+	var states []map[int]int
+	var ends []int64
+	expected := map[int]int{}
+	for i := 0; i < 1500; i++ {
+		key := rnd.Intn(500)
+		if rnd.Intn(3) == 0 {
+			m.Delete(key)
+			delete(expected, key)
+		} else {
+			m.Set(key, i)
+			expected[key] = i
+		}
+		if i == 1000 {
+			if err := m.Checkpoint(); err != nil {
+				t.Fatal("Error:", err)
+			}
+			states = append(states, maps.Clone(expected))
+			ends = append(ends, 0)
+		} else if i > 1000 {
+			states = append(states, maps.Clone(expected))
+			ends = append(ends, m.(*durableMapImpl[int, int]).log.size)
+		}
+	}
+	if err := m.Sync(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	crashDurableMap(m)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	log, err := os.ReadFile(path + ".wal")
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	if int64(len(log)) != ends[len(ends)-1] {
+		t.Fatalf("Expected log size = %d, got %d", ends[len(ends)-1], len(log))
+	}
+	cuts := []int{0, len(log)}
+	for i := 0; i < 100; i++ {
+		cuts = append(cuts, rnd.Intn(len(log)+1))
+	}
+	for _, end := range ends[1:20] {
+		cuts = append(cuts, int(end)-1, int(end), int(end)+1)
+	}
+	for i, cut := range cuts {
+		path := filepath.Join(dir, fmt.Sprint("crash", i))
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal("Error:", err)
+		}
+		if err := os.WriteFile(path+".wal", log[:cut], 0o644); err != nil {
+			t.Fatal("Error:", err)
+		}
+		// Find the last operation that is entirely written to log.
+		j := len(ends) - 1
+		for ends[j] > int64(cut) {
+			j--
+		}
+		m := openTestDurableMap(t, path, SyncBatch)
+		testCheckDurableMap(t, m, states[j])
+		if v := m.(*durableMapImpl[int, int]).log.size; v != ends[j] {
+			t.Fatalf("Expected log size = %d, got %d", ends[j], v)
+		}
+		crashDurableMap(m)
+	}
+}
+
+func TestDurableMapCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m, err := OpenDurableMap(path, intLess, DurableMapOptions[int, int]{
+		DiskMapOptions: testDiskOptions,
+		SyncPolicy:     SyncNone,
+		CheckpointSize: 4096,
+	})
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	expected := map[int]int{}
+	for i := 0; i < 10000; i++ {
+		m.Set(i%3000, i)
+		expected[i%3000] = i
+		if v := m.(*durableMapImpl[int, int]).log.size; v >= 4096 {
+			t.Fatalf("Expected log size < %d, got %d", 4096, v)
+		}
+	}
+	crashDurableMap(m)
+	m = openTestDurableMap(t, path, SyncNone)
+	defer func() { _ = m.Close() }()
+	testCheckDurableMap(t, m, expected)
+}
+
+func TestDurableMapCheckpointError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m := openTestDurableMap(t, path, SyncAlways)
+	expected := map[int]int{}
+	for i := 0; i < 100; i++ {
+		m.Set(i, i)
+		expected[i] = i
+	}
+	// File of tree is closed under pager, so checkpoint fails.
+	impl := m.(*durableMapImpl[int, int])
+	if err := impl.tree.pager.file.Close(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	if err := m.Checkpoint(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Expected error %v, got %v", os.ErrClosed, err)
+	}
+	if impl.log.size == 0 {
+		t.Fatal("Log should be kept")
+	}
+	if err := m.Close(); err == nil {
+		t.Fatal("Expected error")
+	}
+	stat, err := os.Stat(path + ".wal")
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	if stat.Size() == 0 {
+		t.Fatal("Log should be kept")
+	}
+	m = openTestDurableMap(t, path, SyncAlways)
+	defer func() { _ = m.Close() }()
+	testCheckDurableMap(t, m, expected)
+}
+
+func TestDurableMapIter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m := openTestDurableMap(t, path, SyncAlways)
+	expected := map[int]int{}
+	for i := 0; i < 100; i++ {
+		m.Set(i, i)
+		expected[i] = i
+	}
+	if err := m.Checkpoint(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	it := m.Iter()
+	for ok := it.First(); ok; {
+		if it.Key()%2 == 0 {
+			delete(expected, it.Key())
+			ok = it.Delete()
+		} else {
+			expected[it.Key()] = -it.Key()
+			it.SetValue(-it.Key())
+			ok = it.Next()
+		}
+	}
+	// Deletion through ended iterator is not written to log.
+	size := m.(*durableMapImpl[int, int]).log.size
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	if v := m.(*durableMapImpl[int, int]).log.size; v != size {
+		t.Fatalf("Expected log size = %d, got %d", size, v)
+	}
+	crashDurableMap(m)
+	m = openTestDurableMap(t, path, SyncAlways)
+	defer func() { _ = m.Close() }()
+	testCheckDurableMap(t, m, expected)
+}
@@ -0,0 +1,197 @@
+package btree
+
+import "iter"
+
+func (m *mapImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := mapIter[K, V]{m: m}
+		walk(&it, it.First(), false, m.less, &m.version, yield)
+	}
+}
+
+func (m *mapImpl[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := mapIter[K, V]{m: m}
+		walk(&it, it.Last(), true, m.less, &m.version, yield)
+	}
+}
+
+func (m *mapImpl[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := mapIter[K, V]{m: m}
+		walk(&it, it.Seek(lo), false, m.less, &m.version, func(key K, value V) bool {
+			return m.less(key, hi) && yield(key, value)
+		})
+	}
+}
+
+func (m *mapImpl[K, V]) RangeFrom(lo K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := mapIter[K, V]{m: m}
+		walk(&it, it.Seek(lo), false, m.less, &m.version, yield)
+	}
+}
+
+func (m *mapImpl[K, V]) Keys() iter.Seq[K] {
+	return keys(m.All())
+}
+
+func (m *mapImpl[K, V]) Values() iter.Seq[V] {
+	return values(m.All())
+}
+
+// walk calls yield for each item starting from current item of
+// iterator until yield returns false.
+//
+// Modification of map invalidates iterator, so in that case walk
+// seeks the following item by key. Modification is detected by
+// change of version of map.
+func walk[K, V any](
+	it MapIter[K, V], ok, backward bool, less func(K, K) bool,
+	version *uint64, yield func(K, V) bool,
+) {
+	for ok {
+		key := it.Key()
+		current := *version
+		if !yield(key, it.Value()) {
+			return
+		}
+		if *version == current {
+			if backward {
+				ok = it.Prev()
+			} else {
+				ok = it.Next()
+			}
+		} else if backward {
+			ok = it.SeekPrev(key)
+			if ok && !less(it.Key(), key) {
+				ok = it.Prev()
+			}
+		} else {
+			ok = it.Seek(key)
+			if ok && !less(key, it.Key()) {
+				ok = it.Next()
+			}
+		}
+	}
+}
+
+// checkVersion panics if map was modified after positioning of
+// iterator, i.e. if current version of map differs from version at
+// the moment of positioning.
+func checkVersion(version, current uint64) {
+	if version != current {
+		panic("map was modified during iteration")
+	}
+}
+
+// keys returns iterator over keys of items.
+func keys[K, V any](items iter.Seq2[K, V]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range items {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// values returns iterator over values of items.
+func values[K, V any](items iter.Seq2[K, V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, value := range items {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// RangeOptions represents options of range iterator.
+type RangeOptions struct {
+	// ExcludeLo excludes lo from range.
+	ExcludeLo bool
+	// IncludeHi includes hi in range.
+	IncludeHi bool
+}
+
+// rangeIter represents iterator that does not move outside of range.
+type rangeIter[K, V any] struct {
+	mapIter[K, V]
+	lo, hi  K
+	options RangeOptions
+}
+
+func (r *rangeIter[K, V]) Next() bool {
+	if !r.seeked {
+		return r.First()
+	}
+	return r.bound(r.mapIter.Next())
+}
+
+func (r *rangeIter[K, V]) Prev() bool {
+	if !r.seeked {
+		return r.Last()
+	}
+	return r.bound(r.mapIter.Prev())
+}
+
+func (r *rangeIter[K, V]) First() bool {
+	ok := r.mapIter.Seek(r.lo)
+	if ok && r.options.ExcludeLo && !r.m.less(r.lo, r.key) {
+		ok = r.mapIter.Next()
+	}
+	return r.bound(ok)
+}
+
+func (r *rangeIter[K, V]) Last() bool {
+	ok := r.mapIter.SeekPrev(r.hi)
+	if ok && !r.options.IncludeHi && !r.m.less(r.key, r.hi) {
+		ok = r.mapIter.Prev()
+	}
+	return r.bound(ok)
+}
+
+func (r *rangeIter[K, V]) Seek(key K) bool {
+	if !r.afterLo(key) {
+		return r.First()
+	}
+	return r.bound(r.mapIter.Seek(key))
+}
+
+func (r *rangeIter[K, V]) SeekPrev(key K) bool {
+	if !r.beforeHi(key) {
+		return r.Last()
+	}
+	return r.bound(r.mapIter.SeekPrev(key))
+}
+
+func (r *rangeIter[K, V]) Delete() bool {
+	return r.bound(r.mapIter.Delete())
+}
+
+// bound makes iterator unpositioned if current item is outside
+// of range.
+func (r *rangeIter[K, V]) bound(ok bool) bool {
+	if ok && (!r.afterLo(r.key) || !r.beforeHi(r.key)) {
+		r.reset()
+		return false
+	}
+	return ok
+}
+
+// afterLo returns true if key satisfies lower bound of range.
+func (r *rangeIter[K, V]) afterLo(key K) bool {
+	if r.options.ExcludeLo {
+		return r.m.less(r.lo, key)
+	}
+	return !r.m.less(key, r.lo)
+}
+
+// beforeHi returns true if key satisfies upper bound of range.
+func (r *rangeIter[K, V]) beforeHi(key K) bool {
+	if r.options.IncludeHi {
+		return !r.m.less(r.hi, key)
+	}
+	return r.m.less(key, r.hi)
+}
@@ -0,0 +1,239 @@
+package btree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMapIterators(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(i*2, i)
+	}
+	var keys []int
+	for k, v := range m.All() {
+		if v*2 != k {
+			t.Fatalf("Expected value = %d, got %d", k/2, v)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) != n || !slices.IsSorted(keys) {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	if v := slices.Collect(m.Keys()); !slices.Equal(v, keys) {
+		t.Fatalf("Invalid keys: %v", v)
+	}
+	if v := slices.Collect(m.Values()); len(v) != n || v[n-1] != n-1 {
+		t.Fatalf("Invalid values: %v", v)
+	}
+	var backward []int
+	for k := range m.Backward() {
+		backward = append(backward, k)
+	}
+	slices.Reverse(backward)
+	if !slices.Equal(backward, keys) {
+		t.Fatalf("Invalid keys: %v", backward)
+	}
+	var rng []int
+	for k := range m.Range(9, 20) {
+		rng = append(rng, k)
+	}
+	if !slices.Equal(rng, []int{10, 12, 14, 16, 18}) {
+		t.Fatalf("Invalid keys: %v", rng)
+	}
+	rng = nil
+	for k := range m.RangeFrom(2*n - 9) {
+		rng = append(rng, k)
+	}
+	if !slices.Equal(rng, []int{2*n - 8, 2*n - 6, 2*n - 4, 2*n - 2}) {
+		t.Fatalf("Invalid keys: %v", rng)
+	}
+	for range m.Range(20, 10) {
+		t.Fatal("Expected empty range")
+	}
+	for range m.RangeFrom(2 * n) {
+		t.Fatal("Expected empty range")
+	}
+}
+
+func TestMapIteratorsModification(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	var keys []int
+	for k := range m.All() {
+		keys = append(keys, k)
+		m.Delete(k)
+		m.Delete(k + 1)
+	}
+	if len(keys) != n/2 || keys[1] != 2 || m.Len() != 0 {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	keys = nil
+	for k, v := range m.Range(0, n+10) {
+		keys = append(keys, k)
+		if k != v {
+			t.Fatalf("Expected value = %d, got %d", k, v)
+		}
+		if k >= n-1 && k < n+5 {
+			m.Set(k+1, k+1)
+		}
+		// Update of value should not skip items.
+		m.Set(k, v)
+	}
+	if len(keys) != n+6 || !slices.IsSorted(keys) {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	keys = nil
+	for k := range m.Backward() {
+		keys = append(keys, k)
+		m.Delete(k)
+		m.Delete(k - 1)
+	}
+	if len(keys) != (n+6)/2 || m.Len() != 0 {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+}
+
+func TestMapIteratorsSplit(t *testing.T) {
+	m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	for i := 0; i < 3; i++ {
+		m.Set(i*10, i)
+	}
+	noop := func(int, bool) (int, bool) {
+		return 0, false
+	}
+	var keys []int
+	for k := range m.All() {
+		keys = append(keys, k)
+		// Split of full leaf should not skip items.
+		m.Compute(k+5, noop)
+	}
+	if !slices.Equal(keys, []int{0, 10, 20}) {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	m = NewMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	for i := 0; i < 3; i++ {
+		m.Set(i*10, i)
+	}
+	it := m.Iter()
+	if !it.First() {
+		t.Fatal("Expected first item")
+	}
+	m.Compute(5, noop)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Expected panic")
+		}
+	}()
+	it.Next()
+}
+
+func BenchmarkBtreeSimpleIntMapAll(b *testing.B) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < b.N; i++ {
+		m.Set(i, i)
+	}
+	b.ResetTimer()
+	for k, v := range m.All() {
+		if k != v {
+			b.Fatalf("Expected value = %d, got %d", k, v)
+		}
+	}
+}
+
+func TestMapIterRange(t *testing.T) {
+	m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	n := 20
+	for i := 0; i < n; i++ {
+		m.Set(i*2, i)
+	}
+	for lo := -2; lo <= 2*n; lo++ {
+		for hi := lo - 1; hi <= 2*n+1; hi++ {
+			for _, options := range []RangeOptions{
+				{},
+				{ExcludeLo: true},
+				{IncludeHi: true},
+				{ExcludeLo: true, IncludeHi: true},
+			} {
+				var keys []int
+				for k := range m.Keys() {
+					if (k > lo || k == lo && !options.ExcludeLo) &&
+						(k < hi || k == hi && options.IncludeHi) {
+						keys = append(keys, k)
+					}
+				}
+				it := m.IterRange(lo, hi, options)
+				var forward []int
+				for it.Next() {
+					forward = append(forward, it.Key())
+				}
+				if !slices.Equal(forward, keys) {
+					t.Fatalf("Invalid keys: %v, expected %v", forward, keys)
+				}
+				var backward []int
+				for it.Prev() {
+					backward = append(backward, it.Key())
+				}
+				slices.Reverse(backward)
+				if !slices.Equal(backward, keys) {
+					t.Fatalf("Invalid keys: %v, expected %v", backward, keys)
+				}
+				if len(keys) == 0 {
+					if it.First() || it.Last() || it.Seek(lo) || it.SeekPrev(hi) {
+						t.Fatal("Iter should be ended")
+					}
+					continue
+				}
+				if !it.First() || it.Key() != keys[0] || it.Prev() {
+					t.Fatal("Invalid first item")
+				}
+				if !it.Last() || it.Key() != keys[len(keys)-1] || it.Next() {
+					t.Fatal("Invalid last item")
+				}
+				for k := lo - 1; k <= hi+1; k++ {
+					i, _ := slices.BinarySearch(keys, k)
+					if ok := it.Seek(k); ok != (i < len(keys)) || ok && it.Key() != keys[i] {
+						t.Fatalf("Invalid seek of key %d", k)
+					}
+					i, found := slices.BinarySearch(keys, k)
+					if !found {
+						i--
+					}
+					if ok := it.SeekPrev(k); ok != (i >= 0) || ok && it.Key() != keys[i] {
+						t.Fatalf("Invalid seek of key %d", k)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMapIterRangeDelete(t *testing.T) {
+	m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	n := 100
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	it := m.IterRange(10, 20, RangeOptions{IncludeHi: true})
+	count := 0
+	for ok := it.First(); ok; ok = it.Delete() {
+		count++
+	}
+	if count != 11 {
+		t.Fatalf("Expected %d removed items, got %d", 11, count)
+	}
+	if v := m.Len(); v != n-11 {
+		t.Fatalf("Expected len = %d, got %d", n-11, v)
+	}
+	if _, ok := m.Get(21); !ok {
+		t.Fatalf("Key %d should exist", 21)
+	}
+	testCheckMap(t, m)
+}
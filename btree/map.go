@@ -1,5 +1,12 @@
 package btree
 
+import (
+	"iter"
+	"slices"
+	"sync/atomic"
+	"unsafe"
+)
+
 type MapIter[K, V any] interface {
 	// Next moves iterator forward.
 	Next() bool
@@ -17,12 +24,20 @@ type MapIter[K, V any] interface {
 	// Seek moves iterator to item with item.key <= key, or 
 	// returns false if there is no such key.
 	SeekPrev(K) bool
-	// Key returns current item key.
+	// Key returns current item key, or empty key if iterator is not
+	// positioned.
 	Key() K
-	// Value returns current item value.
+	// Value returns current item value, or empty value if iterator
+	// is not positioned.
 	Value() V
 	// SetValue sets value of current item.
 	SetValue(value V)
+	// Delete removes current item from map and moves iterator to
+	// next item, or returns false if there is no such item.
+	//
+	// If iterator is not positioned, Delete returns false and does
+	// not modify map.
+	Delete() bool
 }
 
 // Map represents map implementation using B-Tree.
@@ -32,29 +47,192 @@ type Map[K, V any] interface {
 	Delete(key K)
 	Len() int
 	Iter() MapIter[K, V]
+	// Union adds all items of other map. Values of keys that are
+	// present in both maps are determined by resolve.
+	//
+	// Set operations take O(k log n) time, where k is the size of other
+	// map, if other map is much smaller than current map. Otherwise
+	// both maps are merged and current map is rebuilt in O(n + k) time.
+	Union(other Map[K, V], resolve func(key K, x, y V) V)
+	// Intersection removes items which keys are missing in other map.
+	// Values of remaining items are determined by resolve.
+	Intersection(other Map[K, V], resolve func(key K, x, y V) V)
+	// Difference removes items which keys are present in other map.
+	Difference(other Map[K, V])
+	// SymmetricDifference removes items which keys are present in
+	// other map and adds items of other map which keys are missing.
+	SymmetricDifference(other Map[K, V])
+	// All returns iterator over all items in ascending order.
+	//
+	// Map can be modified during iteration: after each modification
+	// iteration continues from the first item with greater key.
+	All() iter.Seq2[K, V]
+	// Backward returns iterator over all items in descending order.
+	//
+	// Map can be modified during iteration: after each modification
+	// iteration continues from the last item with smaller key.
+	Backward() iter.Seq2[K, V]
+	// Range returns iterator over items with lo <= key < hi in
+	// ascending order.
+	Range(lo, hi K) iter.Seq2[K, V]
+	// RangeFrom returns iterator over items with key >= lo in
+	// ascending order.
+	RangeFrom(lo K) iter.Seq2[K, V]
+	// Keys returns iterator over all keys in ascending order.
+	Keys() iter.Seq[K]
+	// Values returns iterator over all values in ascending order
+	// of keys.
+	Values() iter.Seq[V]
+	// Upsert sets value returned by fn and returns it. Function fn
+	// receives current value and flag that key exists.
+	Upsert(key K, fn func(old V, exists bool) V) V
+	// GetOrSet returns current value and true if key exists, otherwise
+	// sets specified value and returns it with false.
+	GetOrSet(key K, value V) (V, bool)
+	// Swap sets value and returns previous value and flag that key
+	// existed.
+	Swap(key K, value V) (V, bool)
+	// Compute sets value returned by fn, or removes item if fn returns
+	// false. Function fn receives current value and flag that key
+	// exists.
+	//
+	// Upsert, GetOrSet, Swap and Compute find key only once.
+	Compute(key K, fn func(old V, exists bool) (V, bool))
+	// Min returns item with smallest key, or returns false if map
+	// is empty.
+	Min() (K, V, bool)
+	// Max returns item with largest key, or returns false if map
+	// is empty.
+	Max() (K, V, bool)
+	// PopMin removes and returns item with smallest key, or returns
+	// false if map is empty.
+	PopMin() (K, V, bool)
+	// PopMax removes and returns item with largest key, or returns
+	// false if map is empty.
+	PopMax() (K, V, bool)
+	// PopN removes and returns at most n items with smallest keys
+	// in ascending order.
+	PopN(n int) ([]K, []V)
+	// DeleteRange removes all items with lo <= key < hi and returns
+	// amount of removed items.
+	//
+	// Subtrees that are entirely in range are removed at once.
+	DeleteRange(lo, hi K) int
+	// Rank returns amount of items with item.key < key.
+	Rank(key K) int
+	// At returns item with specified index in ascending order, or
+	// returns false if index is out of range.
+	At(i int) (K, V, bool)
+	// IterAt returns iterator positioned at item with specified index.
+	//
+	// If index is out of range, iterator is not positioned.
+	IterAt(i int) MapIter[K, V]
+	// CountRange returns amount of items with lo <= key < hi.
+	CountRange(lo, hi K) int
+	// IterRange returns iterator over items with keys between lo and
+	// hi. By default range includes lo and excludes hi.
+	//
+	// Iterator never moves outside of range: Next, Prev, Seek and
+	// other methods return false when there is no such item in range.
+	// Unpositioned iterator moves to first item on Next and to last
+	// item on Prev, so range can be iterated in both directions.
+	IterRange(lo, hi K, options RangeOptions) MapIter[K, V]
+	// Clone returns copy of map in O(1) time.
+	//
+	// Nodes are shared between copies and are copied only when
+	// shared node is modified. So clone can be used as snapshot
+	// that can be read concurrently with modification of original
+	// map. Clone does not modify map, so it can be called
+	// concurrently with other read-only operations.
+	Clone() Map[K, V]
 }
 
 func NewMap[K, V any](less func(K, K) bool) Map[K, V] {
-	return &mapImpl[K, V]{less: less}
+	return NewMapWithOptions[K, V](less, MapOptions{})
 }
 
-const (
-	mapDegree = 32
-	maxLen    = mapDegree*2 - 1
-	minLen    = maxLen / 2
-)
+// MapOptions represents options of map.
+type MapOptions struct {
+	// Degree represents minimum degree of B-Tree: each node except
+	// root contains from Degree-1 to 2*Degree-1 keys.
+	//
+	// Degree should be at least 2.
+	Degree int
+	// NodeSize represents target size of keys and values of single
+	// node in bytes. NodeSize is used only when Degree is zero.
+	NodeSize int
+}
+
+// NewMapWithOptions creates new map with specified options.
+//
+// If neither Degree nor NodeSize is specified, default degree is used.
+func NewMapWithOptions[K, V any](less func(K, K) bool, options MapOptions) Map[K, V] {
+	m := newMapImpl[K, V](less, options)
+	return &m
+}
+
+// mapDegree represents default degree of map.
+const mapDegree = 32
 
 type mapNode[K, V any] struct {
-	keys     [maxLen]K
-	values   [maxLen]V
-	children *[maxLen + 1]*mapNode[K, V]
-	len      int
+	keys     []K
+	values   []V
+	children []*mapNode[K, V]
+	// counts contains amount of items in subtree of each child.
+	counts []int
+	len    int
+	owner  *mapOwner
+}
+
+// mapOwner represents token of map that can modify node in place.
+//
+// Nodes of other owners are shared with clones of map, so they
+// should be copied before modification.
+type mapOwner struct {
+	// shared is set by Clone when all nodes of owner become shared.
+	// It is atomic, so source map is not modified by Clone.
+	shared atomic.Bool
 }
 
 type mapImpl[K, V any] struct {
 	root *mapNode[K, V]
 	less func(K, K) bool
 	len  int
+	// version is changed on each modification of map.
+	version uint64
+	owner   *mapOwner
+	// maxLen and minLen represent bounds of node len.
+	maxLen int
+	minLen int
+}
+
+func newMapImpl[K, V any](less func(K, K) bool, options MapOptions) mapImpl[K, V] {
+	maxLen := mapMaxLen[K, V](options)
+	return mapImpl[K, V]{
+		less:   less,
+		owner:  &mapOwner{},
+		maxLen: maxLen,
+		minLen: maxLen / 2,
+	}
+}
+
+// mapMaxLen returns maximal amount of keys in node.
+func mapMaxLen[K, V any](options MapOptions) int {
+	degree := options.Degree
+	if degree == 0 && options.NodeSize > 0 {
+		var key K
+		var value V
+		size := int(unsafe.Sizeof(key) + unsafe.Sizeof(value))
+		degree = (options.NodeSize/max(size, 1) + 1) / 2
+		degree = max(degree, 2)
+	}
+	if degree == 0 {
+		degree = mapDegree
+	}
+	if degree < 2 {
+		panic("degree should be at least 2")
+	}
+	return degree*2 - 1
 }
 
 func (m *mapImpl[K, V]) Get(key K) (V, bool) {
@@ -76,15 +254,140 @@ func (m *mapImpl[K, V]) Get(key K) (V, bool) {
 }
 
 func (m *mapImpl[K, V]) Set(key K, value V) {
-	m.setRootNode(key, value)
+	m.compute(key, func(V, bool) (V, bool) {
+		return value, true
+	}, true)
+}
+
+func (m *mapImpl[K, V]) Upsert(key K, fn func(old V, exists bool) V) V {
+	var result V
+	m.compute(key, func(old V, exists bool) (V, bool) {
+		result = fn(old, exists)
+		return result, true
+	}, true)
+	return result
+}
+
+func (m *mapImpl[K, V]) GetOrSet(key K, value V) (V, bool) {
+	var result V
+	var found bool
+	m.compute(key, func(old V, exists bool) (V, bool) {
+		if exists {
+			result, found = old, true
+			return old, true
+		}
+		result = value
+		return value, true
+	}, false)
+	return result, found
+}
+
+func (m *mapImpl[K, V]) Swap(key K, value V) (V, bool) {
+	var result V
+	var found bool
+	m.compute(key, func(old V, exists bool) (V, bool) {
+		result, found = old, exists
+		return value, true
+	}, true)
+	return result, found
+}
+
+func (m *mapImpl[K, V]) Compute(key K, fn func(old V, exists bool) (V, bool)) {
+	m.compute(key, fn, true)
 }
 
 func (m *mapImpl[K, V]) Delete(key K) {
 	if m.root == nil {
 		return
 	}
-	m.deleteNode(&m.root, key)
-	if m.root.len == 0 && m.root.children != nil {
+	if m.deleteNode(&m.root, key) {
+		m.version++
+		m.shrinkRoot()
+	}
+}
+
+func (m *mapImpl[K, V]) Min() (K, V, bool) {
+	if m.root == nil {
+		var emptyKey K
+		var emptyValue V
+		return emptyKey, emptyValue, false
+	}
+	n := m.root
+	for n.children != nil {
+		n = n.children[0]
+	}
+	return n.keys[0], n.values[0], true
+}
+
+func (m *mapImpl[K, V]) Max() (K, V, bool) {
+	if m.root == nil {
+		var emptyKey K
+		var emptyValue V
+		return emptyKey, emptyValue, false
+	}
+	n := m.root
+	for n.children != nil {
+		n = n.children[n.len]
+	}
+	return n.keys[n.len-1], n.values[n.len-1], true
+}
+
+func (m *mapImpl[K, V]) PopMin() (K, V, bool) {
+	if m.root == nil {
+		var emptyKey K
+		var emptyValue V
+		return emptyKey, emptyValue, false
+	}
+	m.version++
+	key, value := m.deleteMinItem(&m.root)
+	m.len--
+	m.shrinkRoot()
+	return key, value, true
+}
+
+func (m *mapImpl[K, V]) PopMax() (K, V, bool) {
+	if m.root == nil {
+		var emptyKey K
+		var emptyValue V
+		return emptyKey, emptyValue, false
+	}
+	m.version++
+	key, value := m.deleteMaxItem(&m.root)
+	m.len--
+	m.shrinkRoot()
+	return key, value, true
+}
+
+func (m *mapImpl[K, V]) PopN(n int) ([]K, []V) {
+	n = min(n, m.len)
+	if n <= 0 {
+		return nil, nil
+	}
+	m.version++
+	keys := make([]K, n)
+	values := make([]V, n)
+	for i := range keys {
+		keys[i], values[i] = m.deleteMinItem(&m.root)
+		m.len--
+		m.shrinkRoot()
+	}
+	return keys, values
+}
+
+func (m *mapImpl[K, V]) DeleteRange(lo, hi K) int {
+	if m.root == nil || !m.less(lo, hi) {
+		return 0
+	}
+	m.version++
+	size := m.len
+	m.deleteRangeNode(&m.root, lo, hi)
+	m.shrinkRoot()
+	return size - m.len
+}
+
+// shrinkRoot removes empty root after deletion of items.
+func (m *mapImpl[K, V]) shrinkRoot() {
+	for m.root.len == 0 && m.root.children != nil {
 		m.root = m.root.children[0]
 	}
 	if m.len == 0 {
@@ -100,6 +403,130 @@ func (m *mapImpl[K, V]) Iter() MapIter[K, V] {
 	return &mapIter[K, V]{m: m}
 }
 
+func (m *mapImpl[K, V]) Rank(key K) int {
+	r := 0
+	n := m.root
+	for n != nil {
+		i, ok := m.search(n, key)
+		r += i
+		if n.children == nil {
+			break
+		}
+		for _, count := range n.counts[:i] {
+			r += count
+		}
+		if ok {
+			r += n.counts[i]
+			break
+		}
+		n = n.children[i]
+	}
+	return r
+}
+
+func (m *mapImpl[K, V]) At(i int) (K, V, bool) {
+	it := mapIter[K, V]{m: m}
+	if !it.seekAt(i) {
+		var emptyKey K
+		var emptyValue V
+		return emptyKey, emptyValue, false
+	}
+	return it.key, *it.value, true
+}
+
+func (m *mapImpl[K, V]) IterAt(i int) MapIter[K, V] {
+	it := mapIter[K, V]{m: m}
+	it.seekAt(i)
+	return &it
+}
+
+func (m *mapImpl[K, V]) IterRange(lo, hi K, options RangeOptions) MapIter[K, V] {
+	return &rangeIter[K, V]{
+		mapIter: mapIter[K, V]{m: m},
+		lo:      lo,
+		hi:      hi,
+		options: options,
+	}
+}
+
+func (m *mapImpl[K, V]) CountRange(lo, hi K) int {
+	if !m.less(lo, hi) {
+		return 0
+	}
+	return m.Rank(hi) - m.Rank(lo)
+}
+
+func (m *mapImpl[K, V]) Clone() Map[K, V] {
+	// All existing nodes become shared, so source map gets new owner
+	// before its next modification.
+	m.owner.shared.Store(true)
+	c := mapImpl[K, V]{
+		root:   m.root,
+		less:   m.less,
+		len:    m.len,
+		owner:  &mapOwner{},
+		maxLen: m.maxLen,
+		minLen: m.minLen,
+	}
+	return &c
+}
+
+// mutableNode returns node that can be modified by current map.
+//
+// If node is shared with other maps, it will be replaced with copy.
+func (m *mapImpl[K, V]) mutableNode(p **mapNode[K, V]) *mapNode[K, V] {
+	owner := m.mutableOwner()
+	n := *p
+	if n.owner == owner {
+		return n
+	}
+	c := *n
+	c.keys = slices.Clone(n.keys)
+	c.values = slices.Clone(n.values)
+	if n.children != nil {
+		c.children = slices.Clone(n.children)
+		c.counts = slices.Clone(n.counts)
+	}
+	c.owner = owner
+	*p = &c
+	return &c
+}
+
+// mutableOwner returns owner of nodes that can be modified in place.
+//
+// If nodes of current owner are shared by Clone, map gets new owner.
+func (m *mapImpl[K, V]) mutableOwner() *mapOwner {
+	if m.owner.shared.Load() {
+		m.owner = &mapOwner{}
+	}
+	return m.owner
+}
+
+// newNode creates empty node with space for maxLen items.
+func (m *mapImpl[K, V]) newNode(internal bool) *mapNode[K, V] {
+	n := mapNode[K, V]{
+		keys:   make([]K, m.maxLen),
+		values: make([]V, m.maxLen),
+		owner:  m.mutableOwner(),
+	}
+	if internal {
+		n.children = make([]*mapNode[K, V], m.maxLen+1)
+		n.counts = make([]int, m.maxLen+1)
+	}
+	return &n
+}
+
+// size returns amount of items in subtree of node.
+func (n *mapNode[K, V]) size() int {
+	size := n.len
+	if n.children != nil {
+		for _, count := range n.counts[:n.len+1] {
+			size += count
+		}
+	}
+	return size
+}
+
 // search returns `pos` that `keys[pos] >= key` and flag that `keys[pos] == key`.
 func (m *mapImpl[K, V]) search(n *mapNode[K, V], key K) (int, bool) {
 	low, high := 0, n.len
@@ -117,168 +544,472 @@ func (m *mapImpl[K, V]) search(n *mapNode[K, V], key K) (int, bool) {
 	return low, false
 }
 
-func (m *mapImpl[K, V]) setRootNode(key K, value V) {
+// compute calls fn for current value by key and applies its result.
+//
+// If fn returns false, item is removed. If overwrite is false, value
+// of existing item is kept. Function fn is called exactly once and map
+// is descended once, except retries after splits of nodes. Shared nodes
+// are copied only if map is modified.
+func (m *mapImpl[K, V]) compute(
+	key K, fn func(old V, exists bool) (V, bool), overwrite bool,
+) {
 	if m.root == nil {
-		m.root = &mapNode[K, V]{len: 1}
-		m.root.keys[0] = key
-		m.root.values[0] = value
-		m.len = 1
+		var empty V
+		value, keep := fn(empty, false)
+		if keep {
+			m.version++
+			m.root = m.newNode(false)
+			m.root.len = 1
+			m.root.keys[0] = key
+			m.root.values[0] = value
+			m.len = 1
+		}
 		return
 	}
-	split := m.setNode(&m.root, key, value)
+	split := m.computeNode(&m.root, key, fn, overwrite)
 	if split {
-		left := m.root
+		// Split changes structure of map even if fn does not modify it.
+		m.version++
+		left := m.mutableNode(&m.root)
 		k, v, right := m.splitNode(left)
-		m.root = &mapNode[K, V]{len: 1}
+		m.root = m.newNode(true)
+		m.root.len = 1
 		m.root.keys[0] = k
 		m.root.values[0] = v
-		m.root.children = &[maxLen + 1]*mapNode[K, V]{left, right}
-		m.setRootNode(key, value)
+		m.root.children[0] = left
+		m.root.children[1] = right
+		m.root.counts[0] = left.size()
+		m.root.counts[1] = right.size()
+		m.compute(key, fn, overwrite)
+		return
 	}
+	m.shrinkRoot()
 }
 
-func (m *mapImpl[K, V]) setNode(p **mapNode[K, V], key K, value V) bool {
+// computeNode returns true if key should be inserted into full node.
+// In that case node is not modified and fn is not called.
+//
+// Node is made mutable only after fn is called and only if it is
+// modified, so lookups of existing keys do not copy shared nodes.
+func (m *mapImpl[K, V]) computeNode(
+	p **mapNode[K, V], key K, fn func(old V, exists bool) (V, bool), overwrite bool,
+) bool {
 	n := *p
-	i, ok := m.search(*p, key)
+	i, ok := m.search(n, key)
 	if ok {
-		n.keys[i] = key
-		n.values[i] = value
+		value, keep := fn(n.values[i], true)
+		if keep && !overwrite {
+			return false
+		}
+		m.version++
+		n = m.mutableNode(p)
+		if keep {
+			n.keys[i] = key
+			n.values[i] = value
+			return false
+		}
+		m.len--
+		if n.children == nil {
+			m.deleteItem(n, i)
+			return false
+		}
+		n.keys[i], n.values[i] = m.deleteMaxItem(&n.children[i])
+		n.counts[i]--
+		if n.children[i].len < m.minLen {
+			m.rebalanceNode(n, i)
+		}
 		return false
 	}
 	if n.children == nil {
-		if n.len == maxLen {
+		if n.len == m.maxLen {
 			return true
 		}
-		copy(n.keys[i+1:], n.keys[i:n.len])
-		copy(n.values[i+1:], n.values[i:n.len])
-		n.keys[i] = key
-		n.values[i] = value
-		n.len++
-		m.len++
+		var empty V
+		value, keep := fn(empty, false)
+		if keep {
+			m.version++
+			n = m.mutableNode(p)
+			copy(n.keys[i+1:], n.keys[i:n.len])
+			copy(n.values[i+1:], n.values[i:n.len])
+			n.keys[i] = key
+			n.values[i] = value
+			n.len++
+			m.len++
+		}
 		return false
 	}
-	split := m.setNode(&n.children[i], key, value)
+	size := m.len
+	child := n.children[i]
+	split := m.computeNode(&child, key, fn, overwrite)
 	if split {
-		if n.len == maxLen {
+		if n.len == m.maxLen {
 			return true
 		}
-		k, v, right := m.splitNode(n.children[i])
+		m.version++
+		n = m.mutableNode(p)
+		k, v, right := m.splitNode(m.mutableNode(&n.children[i]))
 		copy(n.keys[i+1:], n.keys[i:n.len])
 		copy(n.values[i+1:], n.values[i:n.len])
 		n.keys[i] = k
 		n.values[i] = v
 		copy(n.children[i+1:], n.children[i:n.len+1])
+		copy(n.counts[i+1:], n.counts[i:n.len+1])
 		n.children[i+1] = right
+		n.counts[i] = n.children[i].size()
+		n.counts[i+1] = right.size()
 		n.len++
-		return m.setNode(p, key, value)
+		return m.computeNode(p, key, fn, overwrite)
+	}
+	// Child that was modified in place is already owned by map, so
+	// node is owned too and it is not copied.
+	if child == n.children[i] && m.len == size {
+		return false
+	}
+	n = m.mutableNode(p)
+	n.children[i] = child
+	n.counts[i] += m.len - size
+	if child.len < m.minLen {
+		m.rebalanceNode(n, i)
 	}
 	return false
 }
 
 func (m *mapImpl[K, V]) splitNode(n *mapNode[K, V]) (K, V, *mapNode[K, V]) {
-	i := maxLen / 2
+	i := m.maxLen / 2
 	key := n.keys[i]
 	value := n.values[i]
-	right := &mapNode[K, V]{len: n.len - i - 1}
-	copy(right.keys[:], n.keys[i+1:])
-	copy(right.values[:], n.values[i+1:])
+	right := m.newNode(n.children != nil)
+	right.len = n.len - i - 1
+	copy(right.keys, n.keys[i+1:])
+	copy(right.values, n.values[i+1:])
 	if n.children != nil {
-		right.children = &[maxLen + 1]*mapNode[K, V]{}
-		copy(right.children[:], n.children[i+1:])
+		copy(right.children, n.children[i+1:])
+		copy(right.counts, n.counts[i+1:])
 	}
 	var emptyKey K
 	var emptyValue V
-	for j := i; j < maxLen; j++ {
+	for j := i; j < m.maxLen; j++ {
 		n.keys[j] = emptyKey
 		n.values[j] = emptyValue
 		if n.children != nil {
 			n.children[j+1] = nil
+			n.counts[j+1] = 0
 		}
 	}
 	n.len = i
 	return key, value, right
 }
 
+// deleteNode returns true if key is deleted from subtree.
+//
+// Node is made mutable only if key is found, so deletion of missing
+// key does not copy shared nodes.
 func (m *mapImpl[K, V]) deleteNode(p **mapNode[K, V], key K) bool {
 	n := *p
 	i, ok := m.search(n, key)
 	if n.children == nil {
 		if ok {
-			copy(n.keys[i:], n.keys[i+1:n.len])
-			copy(n.values[i:], n.values[i+1:n.len])
-			n.len--
-			var emptyKey K
-			var emptyValue V
-			n.keys[n.len] = emptyKey
-			n.values[n.len] = emptyValue
+			n = m.mutableNode(p)
+			m.deleteItem(n, i)
 			m.len--
 			return true
 		}
 		return false
 	}
-	deleted := false
 	if ok {
-		n.keys[i], n.values[i] = m.deleteMaxItem(n.children[i])
+		n = m.mutableNode(p)
+		n.keys[i], n.values[i] = m.deleteMaxItem(&n.children[i])
 		m.len--
-		deleted = true
 	} else {
-		deleted = m.deleteNode(&n.children[i], key)
-	}
-	if !deleted {
-		return false
+		child := n.children[i]
+		if !m.deleteNode(&child, key) {
+			return false
+		}
+		n = m.mutableNode(p)
+		n.children[i] = child
 	}
-	if n.children[i].len < minLen {
+	n.counts[i]--
+	if n.children[i].len < m.minLen {
 		m.rebalanceNode(n, i)
 	}
 	return true
 }
 
-func (m *mapImpl[K, V]) deleteMaxItem(n *mapNode[K, V]) (K, V) {
-	for {
-		if n.children == nil {
-			n.len--
-			key := n.keys[n.len]
-			value := n.values[n.len]
-			var emptyKey K
-			var emptyValue V
-			n.keys[n.len] = emptyKey
-			n.values[n.len] = emptyValue
-			return key, value
+// deleteItem removes item from leaf.
+func (m *mapImpl[K, V]) deleteItem(n *mapNode[K, V], i int) {
+	copy(n.keys[i:], n.keys[i+1:n.len])
+	copy(n.values[i:], n.values[i+1:n.len])
+	n.len--
+	var emptyKey K
+	var emptyValue V
+	n.keys[n.len] = emptyKey
+	n.values[n.len] = emptyValue
+}
+
+func (m *mapImpl[K, V]) deleteMinItem(p **mapNode[K, V]) (K, V) {
+	n := m.mutableNode(p)
+	if n.children == nil {
+		key := n.keys[0]
+		value := n.values[0]
+		m.deleteItem(n, 0)
+		return key, value
+	}
+	key, value := m.deleteMinItem(&n.children[0])
+	n.counts[0]--
+	if n.children[0].len < m.minLen {
+		m.rebalanceNode(n, 0)
+	}
+	return key, value
+}
+
+func (m *mapImpl[K, V]) deleteMaxItem(p **mapNode[K, V]) (K, V) {
+	n := m.mutableNode(p)
+	if n.children == nil {
+		n.len--
+		key := n.keys[n.len]
+		value := n.values[n.len]
+		var emptyKey K
+		var emptyValue V
+		n.keys[n.len] = emptyKey
+		n.values[n.len] = emptyValue
+		return key, value
+	}
+	key, value := m.deleteMaxItem(&n.children[n.len])
+	n.counts[n.len]--
+	if n.children[n.len].len < m.minLen {
+		m.rebalanceNode(n, n.len)
+	}
+	return key, value
+}
+
+// deleteRangeNode removes items with lo <= key < hi from subtree.
+//
+// After deletion root of subtree can contain any amount of items,
+// so parent should fix it using fixChild.
+func (m *mapImpl[K, V]) deleteRangeNode(p **mapNode[K, V], lo, hi K) {
+	n := m.mutableNode(p)
+	i, _ := m.search(n, lo)
+	j, _ := m.search(n, hi)
+	if n.children == nil {
+		copy(n.keys[i:], n.keys[j:n.len])
+		copy(n.values[i:], n.values[j:n.len])
+		clear(n.keys[n.len-(j-i) : n.len])
+		clear(n.values[n.len-(j-i) : n.len])
+		n.len -= j - i
+		m.len -= j - i
+		return
+	}
+	size := m.len
+	m.deleteRangeNode(&n.children[i], lo, hi)
+	n.counts[i] += m.len - size
+	if i == j {
+		m.fixChild(n, i)
+		return
+	}
+	size = m.len
+	m.deleteRangeNode(&n.children[j], lo, hi)
+	n.counts[j] += m.len - size
+	// Children between i and j are entirely in range, so they are
+	// removed with their separators. Key j-1 is kept as separator
+	// between children i and j until they are fixed.
+	key := n.keys[j-1]
+	for _, count := range n.counts[i+1 : j] {
+		m.len -= count
+	}
+	k := j - 1 - i
+	m.len -= k
+	copy(n.keys[i:], n.keys[j-1:n.len])
+	copy(n.values[i:], n.values[j-1:n.len])
+	copy(n.children[i+1:], n.children[j:n.len+1])
+	copy(n.counts[i+1:], n.counts[j:n.len+1])
+	clear(n.keys[n.len-k : n.len])
+	clear(n.values[n.len-k : n.len])
+	clear(n.children[n.len-k+1 : n.len+1])
+	clear(n.counts[n.len-k+1 : n.len+1])
+	n.len -= k
+	m.fixChild(n, i+1)
+	m.fixChild(n, min(i, n.len))
+	if n.len > 0 {
+		m.deleteNode(p, key)
+		return
+	}
+	// All children are merged into one, so separator is in that child.
+	m.deleteChainNode(p, key)
+}
+
+// deleteChainNode deletes key from subtree whose root can be chain of
+// nodes without items.
+func (m *mapImpl[K, V]) deleteChainNode(p **mapNode[K, V], key K) {
+	if n := *p; n.len > 0 || n.children == nil {
+		m.deleteNode(p, key)
+		return
+	}
+	n := m.mutableNode(p)
+	size := m.len
+	m.deleteChainNode(&n.children[0], key)
+	n.counts[0] += m.len - size
+}
+
+// fixChild restores amount of items in child that can be underfull
+// by any amount.
+//
+// Child is merged with siblings while it is possible, otherwise items
+// are evenly redistributed between child and sibling. Child without
+// items contains single child that can be underfull too, so it is
+// fixed after moving to the new parent.
+func (m *mapImpl[K, V]) fixChild(n *mapNode[K, V], i int) {
+	for n.len > 0 {
+		j := min(i, n.len-1)
+		left := n.children[j]
+		right := n.children[j+1]
+		if left.len >= m.minLen && right.len >= m.minLen {
+			return
 		}
-		n = n.children[n.len]
+		leftNext, rightNext := left.onlyChild(), right.onlyChild()
+		if left.len+right.len < m.maxLen {
+			m.mergeChildren(n, j)
+			i = j
+		} else {
+			m.moveItems(n, j, (left.len-right.len)/2)
+		}
+		m.fixGrandchild(n, j, leftNext)
+		m.fixGrandchild(n, j, rightNext)
+	}
+}
+
+// fixGrandchild fixes node that was moved from child without items
+// to child j or j+1.
+func (m *mapImpl[K, V]) fixGrandchild(n *mapNode[K, V], j int, next *mapNode[K, V]) {
+	if next == nil {
+		return
+	}
+	for i := j; i <= min(j+1, n.len); i++ {
+		child := n.children[i]
+		for k := 0; k <= child.len; k++ {
+			if child.children[k] == next {
+				m.fixChild(m.mutableNode(&n.children[i]), k)
+				return
+			}
+		}
+	}
+}
+
+// onlyChild returns single child of internal node without items.
+func (n *mapNode[K, V]) onlyChild() *mapNode[K, V] {
+	if n.len == 0 && n.children != nil {
+		return n.children[0]
+	}
+	return nil
+}
+
+// moveItems moves k items from child i to child i+1 through separator
+// keys[i]. If k is negative, then -k items are moved backward.
+func (m *mapImpl[K, V]) moveItems(n *mapNode[K, V], i, k int) {
+	left := m.mutableNode(&n.children[i])
+	right := m.mutableNode(&n.children[i+1])
+	if k > 0 {
+		copy(right.keys[k:], right.keys[:right.len])
+		copy(right.values[k:], right.values[:right.len])
+		right.keys[k-1] = n.keys[i]
+		right.values[k-1] = n.values[i]
+		copy(right.keys, left.keys[left.len-k+1:left.len])
+		copy(right.values, left.values[left.len-k+1:left.len])
+		n.keys[i] = left.keys[left.len-k]
+		n.values[i] = left.values[left.len-k]
+		moved := k
+		if left.children != nil {
+			copy(right.children[k:], right.children[:right.len+1])
+			copy(right.counts[k:], right.counts[:right.len+1])
+			copy(right.children, left.children[left.len-k+1:left.len+1])
+			copy(right.counts, left.counts[left.len-k+1:left.len+1])
+			for _, count := range right.counts[:k] {
+				moved += count
+			}
+			clear(left.children[left.len-k+1 : left.len+1])
+			clear(left.counts[left.len-k+1 : left.len+1])
+		}
+		clear(left.keys[left.len-k : left.len])
+		clear(left.values[left.len-k : left.len])
+		left.len -= k
+		right.len += k
+		n.counts[i] -= moved
+		n.counts[i+1] += moved
+	} else if k < 0 {
+		k = -k
+		left.keys[left.len] = n.keys[i]
+		left.values[left.len] = n.values[i]
+		copy(left.keys[left.len+1:], right.keys[:k-1])
+		copy(left.values[left.len+1:], right.values[:k-1])
+		n.keys[i] = right.keys[k-1]
+		n.values[i] = right.values[k-1]
+		moved := k
+		if left.children != nil {
+			copy(left.children[left.len+1:], right.children[:k])
+			copy(left.counts[left.len+1:], right.counts[:k])
+			for _, count := range right.counts[:k] {
+				moved += count
+			}
+			copy(right.children, right.children[k:right.len+1])
+			copy(right.counts, right.counts[k:right.len+1])
+			clear(right.children[right.len-k+1 : right.len+1])
+			clear(right.counts[right.len-k+1 : right.len+1])
+		}
+		copy(right.keys, right.keys[k:right.len])
+		copy(right.values, right.values[k:right.len])
+		clear(right.keys[right.len-k : right.len])
+		clear(right.values[right.len-k : right.len])
+		left.len += k
+		right.len -= k
+		n.counts[i] += moved
+		n.counts[i+1] -= moved
 	}
 }
 
+// mergeChildren merges child i, separator keys[i] and child i+1
+// into single node.
+func (m *mapImpl[K, V]) mergeChildren(n *mapNode[K, V], i int) {
+	left := n.children[i]
+	right := n.children[i+1]
+	node := m.newNode(left.children != nil)
+	node.len = left.len + right.len + 1
+	copy(node.keys, left.keys[:left.len])
+	copy(node.values, left.values[:left.len])
+	node.keys[left.len] = n.keys[i]
+	node.values[left.len] = n.values[i]
+	copy(node.keys[left.len+1:], right.keys[:right.len])
+	copy(node.values[left.len+1:], right.values[:right.len])
+	if left.children != nil {
+		copy(node.children, left.children[:left.len+1])
+		copy(node.children[left.len+1:], right.children[:right.len+1])
+		copy(node.counts, left.counts[:left.len+1])
+		copy(node.counts[left.len+1:], right.counts[:right.len+1])
+	}
+	copy(n.keys[i:], n.keys[i+1:n.len])
+	copy(n.values[i:], n.values[i+1:n.len])
+	copy(n.children[i+1:], n.children[i+2:n.len+1])
+	n.counts[i] += n.counts[i+1] + 1
+	copy(n.counts[i+1:], n.counts[i+2:n.len+1])
+	n.children[i] = node
+	n.children[n.len] = nil
+	n.counts[n.len] = 0
+	n.len--
+	var emptyKey K
+	var emptyValue V
+	n.keys[n.len] = emptyKey
+	n.values[n.len] = emptyValue
+}
+
 func (m *mapImpl[K, V]) rebalanceNode(n *mapNode[K, V], i int) {
 	if i == n.len {
 		i--
 	}
 	left := n.children[i]
 	right := n.children[i+1]
-	if left.len+right.len < maxLen {
-		node := &mapNode[K, V]{len: left.len + right.len + 1}
-		copy(node.keys[:], left.keys[:left.len])
-		copy(node.values[:], left.values[:left.len])
-		node.keys[left.len] = n.keys[i]
-		node.values[left.len] = n.values[i]
-		copy(node.keys[left.len+1:], right.keys[:right.len])
-		copy(node.values[left.len+1:], right.values[:right.len])
-		if left.children != nil {
-			node.children = &[maxLen + 1]*mapNode[K, V]{}
-			copy(node.children[:], left.children[:left.len+1])
-			copy(node.children[left.len+1:], right.children[:right.len+1])
-		}
-		copy(n.keys[i:], n.keys[i+1:n.len])
-		copy(n.values[i:], n.values[i+1:n.len])
-		copy(n.children[i+1:], n.children[i+2:n.len+1])
-		n.children[i] = node
-		n.children[n.len] = nil
-		n.len--
-		var emptyKey K
-		var emptyValue V
-		n.keys[n.len] = emptyKey
-		n.values[n.len] = emptyValue
+	if left.len+right.len < m.maxLen {
+		m.mergeChildren(n, i)
 	} else if left.len > right.len {
+		left = m.mutableNode(&n.children[i])
+		right = m.mutableNode(&n.children[i+1])
 		copy(right.keys[1:], right.keys[:right.len])
 		copy(right.values[1:], right.values[:right.len])
 		right.keys[0] = n.keys[i]
@@ -286,17 +1017,26 @@ func (m *mapImpl[K, V]) rebalanceNode(n *mapNode[K, V], i int) {
 		right.len++
 		n.keys[i] = left.keys[left.len-1]
 		n.values[i] = left.values[left.len-1]
+		moved := 1
 		if left.children != nil {
 			copy(right.children[1:], right.children[:right.len])
+			copy(right.counts[1:], right.counts[:right.len])
 			right.children[0] = left.children[left.len]
+			right.counts[0] = left.counts[left.len]
+			moved += left.counts[left.len]
 			left.children[left.len] = nil
+			left.counts[left.len] = 0
 		}
+		n.counts[i] -= moved
+		n.counts[i+1] += moved
 		left.len--
 		var emptyKey K
 		var emptyValue V
 		left.keys[left.len] = emptyKey
 		left.values[left.len] = emptyValue
 	} else {
+		left = m.mutableNode(&n.children[i])
+		right = m.mutableNode(&n.children[i+1])
 		left.keys[left.len] = n.keys[i]
 		left.values[left.len] = n.values[i]
 		left.len++
@@ -304,11 +1044,18 @@ func (m *mapImpl[K, V]) rebalanceNode(n *mapNode[K, V], i int) {
 		n.values[i] = right.values[0]
 		copy(right.keys[:], right.keys[1:right.len])
 		copy(right.values[:], right.values[1:right.len])
+		moved := 1
 		if left.children != nil {
 			left.children[left.len] = right.children[0]
+			left.counts[left.len] = right.counts[0]
+			moved += right.counts[0]
 			copy(right.children[:], right.children[1:right.len+1])
+			copy(right.counts[:], right.counts[1:right.len+1])
 			right.children[right.len] = nil
+			right.counts[right.len] = 0
 		}
+		n.counts[i] += moved
+		n.counts[i+1] -= moved
 		right.len--
 		var emptyKey K
 		var emptyValue V
@@ -317,12 +1064,18 @@ func (m *mapImpl[K, V]) rebalanceNode(n *mapNode[K, V], i int) {
 	}
 }
 
+// mapIter represents iterator over map.
+//
+// Any modification of map except modifications through iterator
+// invalidates iterator, so further usage of iterator panics.
 type mapIter[K, V any] struct {
 	m      *mapImpl[K, V]
 	stack  []mapIterPos[K, V]
 	key    K
 	value  *V
 	seeked bool
+	// version is version of map at the moment of positioning.
+	version uint64
 }
 
 type mapIterPos[K, V any] struct {
@@ -335,6 +1088,7 @@ func (m *mapIter[K, V]) First() bool {
 		return false
 	}
 	m.seeked = true
+	m.version = m.m.version
 	m.stack = m.stack[:0]
 	n := m.m.root
 	for {
@@ -354,6 +1108,7 @@ func (m *mapIter[K, V]) Last() bool {
 		return false
 	}
 	m.seeked = true
+	m.version = m.m.version
 	m.stack = m.stack[:0]
 	n := m.m.root
 	for {
@@ -373,6 +1128,7 @@ func (m *mapIter[K, V]) Next() bool {
 	if !m.seeked {
 		return m.First()
 	}
+	m.check()
 	s := &m.stack[len(m.stack)-1]
 	s.i++
 	if s.n.children == nil {
@@ -412,6 +1168,7 @@ func (m *mapIter[K, V]) Prev() bool {
 	if !m.seeked {
 		return m.Last()
 	}
+	m.check()
 	s := &m.stack[len(m.stack)-1]
 	if s.n.children == nil {
 		s.i--
@@ -454,6 +1211,7 @@ func (m *mapIter[K, V]) Seek(key K) bool {
 		return false
 	}
 	m.seeked = true
+	m.version = m.m.version
 	m.stack = m.stack[:0]
 	n := m.m.root
 	for {
@@ -477,6 +1235,7 @@ func (m *mapIter[K, V]) SeekPrev(key K) bool {
 		return false
 	}
 	m.seeked = true
+	m.version = m.m.version
 	m.stack = m.stack[:0]
 	n := m.m.root
 	for {
@@ -494,14 +1253,95 @@ func (m *mapIter[K, V]) SeekPrev(key K) bool {
 	}
 }
 
+// seekAt moves iterator to item with specified index, or returns
+// false if index is out of range.
+func (m *mapIter[K, V]) seekAt(index int) bool {
+	if index < 0 || index >= m.m.len {
+		return false
+	}
+	m.seeked = true
+	m.version = m.m.version
+	m.stack = m.stack[:0]
+	n := m.m.root
+	for {
+		if n.children == nil {
+			m.stack = append(m.stack, mapIterPos[K, V]{n, index})
+			m.key = n.keys[index]
+			m.value = &n.values[index]
+			return true
+		}
+		// Skip children and keys that precede item.
+		i := 0
+		for index > n.counts[i] {
+			index -= n.counts[i] + 1
+			i++
+		}
+		m.stack = append(m.stack, mapIterPos[K, V]{n, i})
+		if index == n.counts[i] {
+			m.key = n.keys[i]
+			m.value = &n.values[i]
+			return true
+		}
+		n = n.children[i]
+	}
+}
+
 func (m *mapIter[K, V]) Key() K {
+	if m.seeked {
+		m.check()
+	}
 	return m.key
 }
 
 func (m *mapIter[K, V]) Value() V {
+	if !m.seeked {
+		var empty V
+		return empty
+	}
+	m.check()
 	return *m.value
 }
 
 func (m *mapIter[K, V]) SetValue(value V) {
+	m.check()
+	// Path to current item should be copied if it is shared.
+	p := &m.m.root
+	for i := range m.stack {
+		s := &m.stack[i]
+		s.n = m.m.mutableNode(p)
+		if i+1 < len(m.stack) {
+			p = &s.n.children[s.i]
+		}
+	}
+	s := m.stack[len(m.stack)-1]
+	m.value = &s.n.values[s.i]
 	*m.value = value
 }
+
+func (m *mapIter[K, V]) Delete() bool {
+	if !m.seeked {
+		return false
+	}
+	m.check()
+	key := m.key
+	m.m.Delete(key)
+	// Key is already removed, so seek moves to the next item.
+	if !m.Seek(key) {
+		m.reset()
+		return false
+	}
+	return true
+}
+
+// reset makes iterator unpositioned.
+func (m *mapIter[K, V]) reset() {
+	m.seeked = false
+	var emptyKey K
+	m.key = emptyKey
+	m.value = nil
+}
+
+// check panics if map was modified after positioning of iterator.
+func (m *mapIter[K, V]) check() {
+	checkVersion(m.version, m.m.version)
+}
@@ -1,7 +1,10 @@
 package btree
 
 import (
+	"fmt"
 	"math/rand"
+	"slices"
+	"sync"
 	"testing"
 )
 
@@ -125,6 +128,63 @@ func TestSimpleIntMap(t *testing.T) {
 	}
 }
 
+// testCheckMap checks that all leaves have the same depth, all nodes
+// are filled at least by half, keys are sorted and subtree counts
+// are valid.
+func testCheckMap(tb testing.TB, m Map[int, int]) {
+	impl := m.(*mapImpl[int, int])
+	if impl.root == nil {
+		if impl.len != 0 {
+			tb.Fatalf("Expected len = %d, got %d", 0, impl.len)
+		}
+		return
+	}
+	depth := -1
+	count := 0
+	var check func(n *mapNode[int, int], level int, lo, hi *int) int
+	check = func(n *mapNode[int, int], level int, lo, hi *int) int {
+		if n.len > impl.maxLen || (n != impl.root && n.len < impl.minLen) {
+			tb.Fatalf("Invalid node len = %d", n.len)
+		}
+		for i := 0; i < n.len; i++ {
+			if (i > 0 && n.keys[i-1] >= n.keys[i]) ||
+				(lo != nil && n.keys[i] <= *lo) ||
+				(hi != nil && n.keys[i] >= *hi) {
+				tb.Fatalf("Key %d is out of order", n.keys[i])
+			}
+		}
+		count += n.len
+		if n.children == nil {
+			if depth == -1 {
+				depth = level
+			} else if depth != level {
+				tb.Fatal("Tree is not balanced")
+			}
+			return n.len
+		}
+		size := n.len
+		for i := 0; i <= n.len; i++ {
+			clo, chi := lo, hi
+			if i > 0 {
+				clo = &n.keys[i-1]
+			}
+			if i < n.len {
+				chi = &n.keys[i]
+			}
+			count := check(n.children[i], level+1, clo, chi)
+			if count != n.counts[i] {
+				tb.Fatalf("Expected count = %d, got %d", count, n.counts[i])
+			}
+			size += count
+		}
+		return size
+	}
+	check(impl.root, 0, nil, nil)
+	if count != impl.len {
+		tb.Fatalf("Expected len = %d, got %d", count, impl.len)
+	}
+}
+
 func TestRandomIntMap(t *testing.T) {
 	m := NewMap[int, int](intLess)
 	rnd := rand.New(rand.NewSource(42))
@@ -157,6 +217,9 @@ func TestRandomIntMap(t *testing.T) {
 			if v := m.Len(); v != n-i-1 {
 				t.Fatalf("Expected len = %d, got %d", n-i-1, v)
 			}
+			if i%100 == 0 {
+				testCheckMap(t, m)
+			}
 		}
 		m.Delete(0)
 		if _, ok := m.Get(n); ok {
@@ -165,6 +228,607 @@ func TestRandomIntMap(t *testing.T) {
 	}
 }
 
+func TestDeepIntMap(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	rnd := rand.New(rand.NewSource(42))
+	n := 100000
+	for i, k := range rnd.Perm(n) {
+		m.Set(k, i)
+	}
+	testCheckMap(t, m)
+	// Deletion of keys from root requires rebalancing of whole path.
+	for i := 0; i < 1000; i++ {
+		m.Delete(m.(*mapImpl[int, int]).root.keys[0])
+		testCheckMap(t, m)
+	}
+	for i, k := range rnd.Perm(n) {
+		m.Delete(k)
+		if i%10000 == 0 {
+			testCheckMap(t, m)
+		}
+	}
+	testCheckMap(t, m)
+}
+
+func TestMapIterDelete(t *testing.T) {
+	m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 3})
+	n := 3000
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	it := m.Iter()
+	ok := it.First()
+	for i := 0; i < n; i++ {
+		if !ok {
+			t.Fatal("Unexpected end of iter")
+		}
+		if v := it.Key(); v != i {
+			t.Fatalf("Expected key = %d, got %d", i, v)
+		}
+		if i%3 == 0 {
+			ok = it.Delete()
+		} else {
+			ok = it.Next()
+		}
+	}
+	if ok {
+		t.Fatal("Iter should be ended")
+	}
+	testCheckMap(t, m)
+	if v := m.Len(); v != n-n/3 {
+		t.Fatalf("Expected len = %d, got %d", n-n/3, v)
+	}
+	for ok = it.Last(); ok; ok = it.Delete() {
+	}
+	if v := m.Len(); v != n-n/3-1 {
+		t.Fatalf("Expected len = %d, got %d", n-n/3-1, v)
+	}
+	for ok = it.First(); ok; ok = it.Delete() {
+	}
+	if v := m.Len(); v != 0 {
+		t.Fatalf("Expected len = %d, got %d", 0, v)
+	}
+	testCheckMap(t, m)
+}
+
+func TestMapIterDeleteUnpositioned(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	it := m.Iter()
+	if it.First() {
+		t.Fatal("Iter should be ended")
+	}
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	m.Set(0, 0)
+	m.Set(1, 1)
+	it = m.Iter()
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	for ok := it.First(); ok; ok = it.Next() {
+	}
+	if it.Key() != 0 || it.Value() != 0 {
+		t.Fatal("Unpositioned iterator should return empty item")
+	}
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	if v := m.Len(); v != 2 {
+		t.Fatalf("Expected len = %d, got %d", 2, v)
+	}
+	if _, ok := m.Get(0); !ok {
+		t.Fatalf("Key %d should exist", 0)
+	}
+}
+
+func TestMapIterInvalidation(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < 100; i++ {
+		m.Set(i, i)
+	}
+	for _, fn := range []func(it MapIter[int, int]){
+		func(it MapIter[int, int]) { it.Next() },
+		func(it MapIter[int, int]) { it.Prev() },
+		func(it MapIter[int, int]) { it.Key() },
+		func(it MapIter[int, int]) { it.Value() },
+		func(it MapIter[int, int]) { it.SetValue(0) },
+		func(it MapIter[int, int]) { it.Delete() },
+	} {
+		it := m.Iter()
+		other := m.Iter()
+		if !it.Seek(50) || !other.Seek(60) {
+			t.Fatal("Unable to seek")
+		}
+		// Modification through other iterator invalidates iterator.
+		other.Delete()
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("Expected panic")
+				}
+			}()
+			fn(it)
+		}()
+		// Seek makes iterator valid again.
+		if !it.Seek(60) || it.Key() != 61 {
+			t.Fatal("Invalid item after seek")
+		}
+		m.Set(60, 60)
+	}
+}
+
+func TestOrderStatistics(t *testing.T) {
+	m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 3})
+	rnd := rand.New(rand.NewSource(42))
+	n := 3000
+	for i, k := range rnd.Perm(n) {
+		m.Set(k*2, i)
+		if i%100 == 0 {
+			testCheckMap(t, m)
+		}
+	}
+	testCheckMap(t, m)
+	for i := 0; i < n; i++ {
+		if v := m.Rank(i * 2); v != i {
+			t.Fatalf("Expected rank = %d, got %d", i, v)
+		}
+		if v := m.Rank(i*2 + 1); v != i+1 {
+			t.Fatalf("Expected rank = %d, got %d", i+1, v)
+		}
+		if k, _, ok := m.At(i); !ok || k != i*2 {
+			t.Fatalf("Expected key = %d, got %d", i*2, k)
+		}
+	}
+	if _, _, ok := m.At(n); ok {
+		t.Fatalf("Index %d should not exist", n)
+	}
+	if _, _, ok := m.At(-1); ok {
+		t.Fatalf("Index %d should not exist", -1)
+	}
+	for _, i := range []int{0, 1, 100, n - 1} {
+		it := m.IterAt(i)
+		for j := i; j < i+10 && j < n; j++ {
+			if v := it.Key(); v != j*2 {
+				t.Fatalf("Expected key = %d, got %d", j*2, v)
+			}
+			if it.Next() != (j+1 < n) {
+				t.Fatalf("Invalid item after %d", j*2)
+			}
+		}
+		it = m.IterAt(i)
+		if i > 0 && (!it.Prev() || it.Key() != i*2-2) {
+			t.Fatalf("Invalid item before %d", i*2)
+		}
+	}
+	if v := m.CountRange(10, 21); v != 6 {
+		t.Fatalf("Expected count = %d, got %d", 6, v)
+	}
+	if v := m.CountRange(21, 10); v != 0 {
+		t.Fatalf("Expected count = %d, got %d", 0, v)
+	}
+	for i, k := range rnd.Perm(n) {
+		if i%2 == 0 {
+			m.Delete(k * 2)
+		}
+		if i%100 == 0 {
+			testCheckMap(t, m)
+		}
+	}
+	testCheckMap(t, m)
+	c := m.Clone()
+	for i := 0; i < c.Len(); i++ {
+		k, _, _ := c.At(i)
+		if v := c.Rank(k); v != i {
+			t.Fatalf("Expected rank = %d, got %d", i, v)
+		}
+		m.Delete(k)
+	}
+	testCheckMap(t, m)
+	testCheckMap(t, c)
+}
+
+func TestUpsertCompute(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < 100; i++ {
+		for j := 0; j <= i; j++ {
+			v := m.Upsert(j, func(old int, exists bool) int {
+				if exists != (j < i) {
+					t.Fatalf("Invalid existence of key %d", j)
+				}
+				return old + 1
+			})
+			if v != i-j+1 {
+				t.Fatalf("Expected value = %d, got %d", i-j+1, v)
+			}
+		}
+	}
+	if v, ok := m.GetOrSet(0, -1); !ok || v != 100 {
+		t.Fatalf("Expected value = %d, got %d", 100, v)
+	}
+	if v, ok := m.GetOrSet(100, -1); ok || v != -1 {
+		t.Fatalf("Expected value = %d, got %d", -1, v)
+	}
+	if v, ok := m.Swap(100, -2); !ok || v != -1 {
+		t.Fatalf("Expected value = %d, got %d", -1, v)
+	}
+	if v, ok := m.Swap(101, -3); ok || v != 0 {
+		t.Fatalf("Expected value = %d, got %d", 0, v)
+	}
+	if v, ok := m.Get(100); !ok || v != -2 {
+		t.Fatalf("Expected value = %d, got %d", -2, v)
+	}
+	rnd := rand.New(rand.NewSource(42))
+	expected := map[int]int{}
+	for k, v := range m.All() {
+		expected[k] = v
+	}
+	for i := 0; i < 10000; i++ {
+		k := rnd.Intn(200)
+		m.Compute(k, func(old int, exists bool) (int, bool) {
+			if v, ok := expected[k]; ok != exists || v != old {
+				t.Fatalf("Expected value = %d, got %d", v, old)
+			}
+			if old%3 == 0 {
+				delete(expected, k)
+				return 0, false
+			}
+			expected[k] = old + i
+			return old + i, true
+		})
+		if v := m.Len(); v != len(expected) {
+			t.Fatalf("Expected len = %d, got %d", len(expected), v)
+		}
+	}
+	for k, v := range m.All() {
+		if expected[k] != v {
+			t.Fatalf("Expected value = %d, got %d", expected[k], v)
+		}
+	}
+	testCheckMap(t, m)
+}
+
+func TestPriorityQueue(t *testing.T) {
+	m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 3})
+	if _, _, ok := m.Min(); ok {
+		t.Fatal("Map should be empty")
+	}
+	if _, _, ok := m.PopMax(); ok {
+		t.Fatal("Map should be empty")
+	}
+	rnd := rand.New(rand.NewSource(42))
+	n := 3000
+	for i, k := range rnd.Perm(n) {
+		m.Set(k, i)
+	}
+	lo, hi := 0, n-1
+	for i := 0; i < 500; i++ {
+		if k, _, ok := m.Min(); !ok || k != lo {
+			t.Fatalf("Expected key = %d, got %d", lo, k)
+		}
+		if k, _, ok := m.Max(); !ok || k != hi {
+			t.Fatalf("Expected key = %d, got %d", hi, k)
+		}
+		if k, _, ok := m.PopMin(); !ok || k != lo {
+			t.Fatalf("Expected key = %d, got %d", lo, k)
+		}
+		if k, _, ok := m.PopMax(); !ok || k != hi {
+			t.Fatalf("Expected key = %d, got %d", hi, k)
+		}
+		lo, hi = lo+1, hi-1
+		if i%50 == 0 {
+			testCheckMap(t, m)
+		}
+	}
+	keys, values := m.PopN(100)
+	if len(keys) != 100 || len(values) != 100 || keys[0] != lo || keys[99] != lo+99 {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	testCheckMap(t, m)
+	if v, ok := m.Get(lo); ok {
+		t.Fatalf("Key %d should not exist", v)
+	}
+	keys, _ = m.PopN(n)
+	if len(keys) != hi-lo-99 || m.Len() != 0 {
+		t.Fatalf("Expected %d keys, got %d", hi-lo-99, len(keys))
+	}
+	testCheckMap(t, m)
+	if keys, _ := m.PopN(1); keys != nil {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+}
+
+func TestDeleteRange(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	for _, n := range []int{0, 10, 100, 3000} {
+		m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 2 + n%4})
+		var keys []int
+		for _, k := range rnd.Perm(n) {
+			m.Set(k*2, k)
+		}
+		for k := 0; k < n; k++ {
+			keys = append(keys, k*2)
+		}
+		for i := 0; i < 100 && len(keys) > 0; i++ {
+			lo := rnd.Intn(2*n+2) - 1
+			hi := lo + rnd.Intn(2*n/(i+1)+2)
+			if i%10 == 0 {
+				hi = lo - 1
+			}
+			l, _ := slices.BinarySearch(keys, lo)
+			r, _ := slices.BinarySearch(keys, hi)
+			r = max(l, r)
+			c := m.Clone()
+			size := c.Len()
+			if v := m.DeleteRange(lo, hi); v != r-l {
+				t.Fatalf("Expected %d removed items, got %d", r-l, v)
+			}
+			testCheckMap(t, m)
+			testCheckMap(t, c)
+			if v := c.Len(); v != size {
+				t.Fatalf("Expected len = %d, got %d", size, v)
+			}
+			keys = slices.Delete(keys, l, r)
+			if v := m.Len(); v != len(keys) {
+				t.Fatalf("Expected len = %d, got %d", len(keys), v)
+			}
+			if v := slices.Collect(m.Keys()); !slices.Equal(v, keys) {
+				t.Fatalf("Invalid keys: %v", v)
+			}
+			for _, k := range keys {
+				if v, ok := m.Get(k); !ok || v != k/2 {
+					t.Fatalf("Expected value = %d, got %d", k/2, v)
+				}
+			}
+		}
+	}
+}
+
+func TestMapOptions(t *testing.T) {
+	for _, options := range []MapOptions{
+		{Degree: 2},
+		{Degree: 3},
+		{Degree: 8},
+		{NodeSize: 64},
+		{NodeSize: 4096},
+	} {
+		m := NewMapWithOptions[int, int](intLess, options)
+		impl := m.(*mapImpl[int, int])
+		if options.Degree != 0 && impl.maxLen != options.Degree*2-1 {
+			t.Fatalf("Expected max len = %d, got %d", options.Degree*2-1, impl.maxLen)
+		}
+		if options.NodeSize != 0 && impl.maxLen*16 > options.NodeSize {
+			t.Fatalf("Node size %d exceeds %d", impl.maxLen*16, options.NodeSize)
+		}
+		rnd := rand.New(rand.NewSource(42))
+		n := 3000
+		for i, k := range rnd.Perm(n) {
+			m.Set(k, i)
+		}
+		testCheckMap(t, m)
+		for i, k := range rnd.Perm(n) {
+			m.Delete(k)
+			if i%100 == 0 {
+				testCheckMap(t, m)
+			}
+		}
+		testCheckMap(t, m)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("Expected panic")
+			}
+		}()
+		NewMapWithOptions[int, int](intLess, MapOptions{Degree: 1})
+	}()
+}
+
+func TestCloneIntMap(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	rnd := rand.New(rand.NewSource(42))
+	n := 10000
+	for i, k := range rnd.Perm(n) {
+		m.Set(k, i)
+	}
+	expected := map[int]int{}
+	for k, v := range m.All() {
+		expected[k] = v
+	}
+	c := m.Clone()
+	for i, k := range rnd.Perm(2 * n) {
+		if i%3 == 0 {
+			m.Delete(k)
+		} else {
+			m.Set(k, -i)
+		}
+	}
+	for it := m.Iter(); it.Next(); {
+		it.SetValue(it.Value() + 1)
+	}
+	testCheckMap(t, m)
+	testCheckMap(t, c)
+	if v := c.Len(); v != len(expected) {
+		t.Fatalf("Expected len = %d, got %d", len(expected), v)
+	}
+	for k, v := range c.All() {
+		if expected[k] != v {
+			t.Fatalf("Expected value = %d, got %d", expected[k], v)
+		}
+	}
+	// Modification of clone should not affect original map.
+	actual := map[int]int{}
+	for k, v := range m.All() {
+		actual[k] = v
+	}
+	for k := range rnd.Perm(n) {
+		c.Delete(k)
+	}
+	c.Set(-1, -1)
+	testCheckMap(t, c)
+	if v := m.Len(); v != len(actual) {
+		t.Fatalf("Expected len = %d, got %d", len(actual), v)
+	}
+	for k, v := range m.All() {
+		if actual[k] != v {
+			t.Fatalf("Expected value = %d, got %d", actual[k], v)
+		}
+	}
+	if _, ok := m.Get(-1); ok {
+		t.Fatalf("Key %d should not exist", -1)
+	}
+}
+
+func TestCloneConcurrentRead(t *testing.T) {
+	m := NewMap[int, int](intLess)
+	n := 10000
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	var wg sync.WaitGroup
+	for j := 0; j < 4; j++ {
+		c := m.Clone()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			i := 0
+			for k, v := range c.All() {
+				if k != i || v != i {
+					t.Errorf("Expected key = %d, got %d", i, k)
+					return
+				}
+				i++
+			}
+			if i != n {
+				t.Errorf("Expected %d items, got %d", n, i)
+			}
+		}()
+		for i := 0; i < n; i += 2 {
+			m.Set(i, -i)
+			m.Delete(i + 1)
+		}
+		for i := 0; i < n; i++ {
+			m.Set(i, i)
+		}
+	}
+	wg.Wait()
+}
+
+func TestCloneConcurrentClone(t *testing.T) {
+	m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	clones := make([]Map[int, int], 4)
+	var wg sync.WaitGroup
+	for j := range clones {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Clone should not modify shared map.
+			c := m.Clone()
+			for i := 0; i < n; i += 2 {
+				c.Set(i, -j)
+			}
+			clones[j] = c
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		m.Delete(i)
+	}
+	testCheckMap(t, m)
+	for j, c := range clones {
+		testCheckMap(t, c)
+		for k, v := range c.All() {
+			if k%2 == 0 && v != -j || k%2 != 0 && v != k {
+				t.Fatalf("Invalid value %d of key %d", v, k)
+			}
+		}
+		if v := c.Len(); v != n {
+			t.Fatalf("Expected len = %d, got %d", n, v)
+		}
+	}
+}
+
+func TestCloneGetOrSet(t *testing.T) {
+	m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	c := m.Clone()
+	root := c.(*mapImpl[int, int]).root
+	for i := 0; i < n; i++ {
+		if v, ok := c.GetOrSet(i, -i); !ok || v != i {
+			t.Fatalf("Expected value = %d, got %d", i, v)
+		}
+		c.Compute(-1, func(int, bool) (int, bool) {
+			return 0, false
+		})
+	}
+	// Lookups of existing keys should not copy shared nodes.
+	if c.(*mapImpl[int, int]).root != root {
+		t.Fatal("Root of clone should not be copied")
+	}
+	if _, ok := c.GetOrSet(n, n); ok {
+		t.Fatalf("Key %d should not exist", n)
+	}
+	if c.(*mapImpl[int, int]).root == root {
+		t.Fatal("Root of clone should be copied")
+	}
+	testCheckMap(t, m)
+	testCheckMap(t, c)
+	if _, ok := m.Get(n); ok {
+		t.Fatalf("Key %d should not exist", n)
+	}
+}
+
+func TestCloneDeleteMissing(t *testing.T) {
+	m := NewMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(i*2, i)
+	}
+	c := m.Clone()
+	root := c.(*mapImpl[int, int]).root
+	it := c.Iter()
+	if !it.First() {
+		t.Fatal("Expected first item")
+	}
+	s := NewSet[int](intLess)
+	s.Add(0)
+	sit := s.Iter()
+	if !sit.First() {
+		t.Fatal("Expected first item")
+	}
+	for i := 0; i < n; i++ {
+		c.Delete(i*2 + 1)
+		s.Remove(i*2 + 1)
+	}
+	// Deletion of missing keys should not copy shared nodes and
+	// should not invalidate iterators.
+	if c.(*mapImpl[int, int]).root != root {
+		t.Fatal("Root of clone should not be copied")
+	}
+	if !it.Next() || it.Key() != 2 {
+		t.Fatalf("Expected key = %d, got %d", 2, it.Key())
+	}
+	if sit.Next() {
+		t.Fatal("Expected end of set")
+	}
+	c.Delete(2)
+	if c.(*mapImpl[int, int]).root == root {
+		t.Fatal("Root of clone should be copied")
+	}
+	testCheckMap(t, m)
+	testCheckMap(t, c)
+	if _, ok := m.Get(2); !ok {
+		t.Fatalf("Key %d should exist", 2)
+	}
+}
+
 func BenchmarkBtreeSimpleIntMapSeqSet(b *testing.B) {
 	m := NewMap[int, int](intLess)
 	for i := 0; i < b.N; i++ {
@@ -248,3 +912,50 @@ func BenchmarkBtreeObjectMapSeqDelete(b *testing.B) {
 		m.Delete(i)
 	}
 }
+
+func benchmarkBtreeDegree(b *testing.B, options MapOptions, fn func(b *testing.B, m Map[int, int])) {
+	for _, degree := range []int{2, 4, 8, 16, 32, 64, 128} {
+		options.Degree = degree
+		b.Run(fmt.Sprintf("Degree%d", degree), func(b *testing.B) {
+			fn(b, NewMapWithOptions[int, int](intLess, options))
+		})
+	}
+}
+
+func BenchmarkBtreeDegreeRandomSet(b *testing.B) {
+	benchmarkBtreeDegree(b, MapOptions{}, func(b *testing.B, m Map[int, int]) {
+		p := rand.New(rand.NewSource(42)).Perm(b.N)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			m.Set(p[i], i)
+		}
+	})
+}
+
+func BenchmarkBtreeDegreeRandomGet(b *testing.B) {
+	benchmarkBtreeDegree(b, MapOptions{}, func(b *testing.B, m Map[int, int]) {
+		p := rand.New(rand.NewSource(42)).Perm(b.N)
+		for i := 0; i < b.N; i++ {
+			m.Set(p[i], i)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, ok := m.Get(p[i]); !ok {
+				b.Fatalf("Unable to find key = %d", p[i])
+			}
+		}
+	})
+}
+
+func BenchmarkBtreeSimpleIntMapPopMin(b *testing.B) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < b.N; i++ {
+		m.Set(i, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if k, _, ok := m.PopMin(); !ok || k != i {
+			b.Fatalf("Expected key = %d, got %d", i, k)
+		}
+	}
+}
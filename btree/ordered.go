@@ -0,0 +1,31 @@
+package btree
+
+import (
+	"iter"
+
+	"github.com/udovin/algo/ordered"
+)
+
+// orderedSource represents methods that are common for Map and PlusMap.
+type orderedSource[K, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V)
+	Delete(key K)
+	Len() int
+	All() iter.Seq2[K, V]
+	Iter() MapIter[K, V]
+}
+
+// AsOrdered returns adapter of Map or PlusMap to common ordered
+// map interface.
+func AsOrdered[K, V any](m orderedSource[K, V]) ordered.Map[K, V] {
+	return orderedMap[K, V]{m}
+}
+
+type orderedMap[K, V any] struct {
+	orderedSource[K, V]
+}
+
+func (m orderedMap[K, V]) Iter() ordered.Iter[K, V] {
+	return m.orderedSource.Iter()
+}
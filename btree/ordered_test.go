@@ -0,0 +1,26 @@
+package btree
+
+import (
+	"testing"
+
+	"github.com/udovin/algo/ordered"
+	"github.com/udovin/algo/ordered/orderedtest"
+)
+
+func TestOrderedMap(t *testing.T) {
+	orderedtest.TestMap(t, func() ordered.Map[int, int] {
+		return AsOrdered(NewMap[int, int](intLess))
+	})
+}
+
+func TestOrderedMapDegree(t *testing.T) {
+	orderedtest.TestMap(t, func() ordered.Map[int, int] {
+		return AsOrdered(NewMapWithOptions[int, int](intLess, MapOptions{Degree: 2}))
+	})
+}
+
+func TestOrderedPlusMap(t *testing.T) {
+	orderedtest.TestMap(t, func() ordered.Map[int, int] {
+		return AsOrdered(NewPlusMapWithOptions[int, int](intLess, MapOptions{Degree: 2}))
+	})
+}
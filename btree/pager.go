@@ -0,0 +1,466 @@
+package btree
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+)
+
+// File of DiskMap consists of pages of fixed size.
+//
+// Page 0 contains two slots for header that are written alternately,
+// so torn write of header does not break previous header. Other pages
+// contain nodes of tree or are free.
+//
+// Pages of nodes are never modified in place after commit: modified
+// node is written to free page and old page is released only after
+// next commit. So file always contains tree of last commit.
+const (
+	diskMagic   = "algobtre"
+	diskVersion = 1
+	// diskSlotSize represents distance between slots of header.
+	diskSlotSize = 512
+	// diskHeaderSize represents size of encoded header.
+	diskHeaderSize = 56
+	// pageHeaderSize represents size of checksum, kind and amount
+	// of items of node page.
+	pageHeaderSize   = 9
+	leafPage         = 1
+	internalPage     = 2
+	defaultPageSize  = 4096
+	minPageSize      = 2 * diskSlotSize
+	defaultCacheSize = 256
+)
+
+// diskHeader represents state of tree at the moment of commit.
+type diskHeader struct {
+	// seq is incremented on each commit.
+	seq      uint64
+	pageSize int
+	root     uint64
+	height   int
+	len      int
+	// pages represents amount of used pages in file.
+	pages uint64
+}
+
+func (h diskHeader) encode(buf []byte) {
+	copy(buf, diskMagic)
+	binary.LittleEndian.PutUint32(buf[8:], diskVersion)
+	binary.LittleEndian.PutUint32(buf[12:], uint32(h.pageSize))
+	binary.LittleEndian.PutUint64(buf[16:], h.seq)
+	binary.LittleEndian.PutUint64(buf[24:], h.root)
+	binary.LittleEndian.PutUint64(buf[32:], uint64(h.len))
+	binary.LittleEndian.PutUint64(buf[40:], h.pages)
+	binary.LittleEndian.PutUint32(buf[48:], uint32(h.height))
+	crc := crc32.ChecksumIEEE(buf[:diskHeaderSize-4])
+	binary.LittleEndian.PutUint32(buf[diskHeaderSize-4:], crc)
+}
+
+func (h *diskHeader) decode(buf []byte) bool {
+	if string(buf[:8]) != diskMagic {
+		return false
+	}
+	crc := crc32.ChecksumIEEE(buf[:diskHeaderSize-4])
+	if binary.LittleEndian.Uint32(buf[diskHeaderSize-4:]) != crc {
+		return false
+	}
+	if binary.LittleEndian.Uint32(buf[8:]) != diskVersion {
+		return false
+	}
+	h.pageSize = int(binary.LittleEndian.Uint32(buf[12:]))
+	h.seq = binary.LittleEndian.Uint64(buf[16:])
+	h.root = binary.LittleEndian.Uint64(buf[24:])
+	h.len = int(binary.LittleEndian.Uint64(buf[32:]))
+	h.pages = binary.LittleEndian.Uint64(buf[40:])
+	h.height = int(binary.LittleEndian.Uint32(buf[48:]))
+	return h.pageSize >= minPageSize && h.pages > 0
+}
+
+// diskNode represents decoded node of DiskMap.
+type diskNode[K, V any] struct {
+	id     uint64
+	keys   []K
+	values []V
+	// sizes contains encoded sizes of items.
+	sizes []int
+	// children contains pages of children or nil for leaf.
+	children []uint64
+	// dirty means that node is modified since it was written.
+	dirty bool
+}
+
+// diskError is used to pass I/O error to public method of DiskMap
+// through panic.
+type diskError struct {
+	err error
+}
+
+// pager reads and writes nodes of DiskMap and caches them.
+type pager[K, V any] struct {
+	file       *os.File
+	keyCodec   Codec[K]
+	valueCodec Codec[V]
+	// header represents last commit.
+	header   diskHeader
+	pageSize int
+	pages    uint64
+	// free contains pages that can be reused.
+	free []uint64
+	// pending contains pages released after last commit. They are
+	// used by committed tree, so they become free after next commit.
+	pending []uint64
+	// fresh contains pages allocated after last commit. They are not
+	// used by committed tree, so they can be modified in place.
+	fresh map[uint64]struct{}
+	// cache contains nodes ordered from recently used to least
+	// recently used.
+	cache     list.List
+	cacheSize int
+	// index contains elements of cache by pages.
+	index map[uint64]*list.Element
+	buf   []byte
+}
+
+func newPager[K, V any](file *os.File, options DiskMapOptions[K, V]) (*pager[K, V], error) {
+	if options.KeyCodec == nil || options.ValueCodec == nil {
+		panic("key and value codecs should be specified")
+	}
+	p := pager[K, V]{
+		file:       file,
+		keyCodec:   options.KeyCodec,
+		valueCodec: options.ValueCodec,
+		fresh:      map[uint64]struct{}{},
+		cacheSize:  options.CacheSize,
+		index:      map[uint64]*list.Element{},
+	}
+	if p.cacheSize == 0 {
+		p.cacheSize = defaultCacheSize
+	}
+	if err := p.readHeader(options.PageSize); err != nil {
+		return nil, err
+	}
+	p.pageSize = p.header.pageSize
+	p.pages = p.header.pages
+	p.buf = make([]byte, p.pageSize)
+	if err := p.findFree(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// readHeader reads the latest valid header or initializes empty file.
+func (p *pager[K, V]) readHeader(pageSize int) error {
+	buf := make([]byte, 2*diskSlotSize)
+	n, err := p.file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if n == 0 {
+		if pageSize == 0 {
+			pageSize = defaultPageSize
+		}
+		if pageSize < minPageSize {
+			panic(fmt.Sprintf("page size should be at least %d", minPageSize))
+		}
+		p.header = diskHeader{pageSize: pageSize, pages: 1}
+		return p.writeHeader(p.header)
+	}
+	clear(buf[n:])
+	found := false
+	for i := 0; i < 2; i++ {
+		var header diskHeader
+		if header.decode(buf[i*diskSlotSize:]) {
+			if !found || header.seq > p.header.seq {
+				p.header = header
+			}
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("header: %w", ErrCorrupted)
+	}
+	return nil
+}
+
+// writeHeader writes header to its slot and syncs file.
+func (p *pager[K, V]) writeHeader(header diskHeader) error {
+	buf := make([]byte, diskHeaderSize)
+	header.encode(buf)
+	offset := int64(header.seq%2) * diskSlotSize
+	if _, err := p.file.WriteAt(buf, offset); err != nil {
+		return err
+	}
+	return p.file.Sync()
+}
+
+// findFree finds pages that are not used by committed tree.
+//
+// Only internal nodes are read, because leaves do not refer to other
+// pages.
+func (p *pager[K, V]) findFree() error {
+	used := make([]bool, p.pages)
+	used[0] = true
+	var visit func(id uint64, height int) error
+	visit = func(id uint64, height int) error {
+		if id == 0 || id >= p.pages || used[id] {
+			return fmt.Errorf("page %d: %w", id, ErrCorrupted)
+		}
+		used[id] = true
+		if height == 1 {
+			return nil
+		}
+		n, err := p.read(id)
+		if err != nil {
+			return err
+		}
+		if n.children == nil {
+			return fmt.Errorf("page %d: %w", id, ErrCorrupted)
+		}
+		for _, child := range n.children {
+			if err := visit(child, height-1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if p.header.height > 0 {
+		if err := visit(p.header.root, p.header.height); err != nil {
+			return err
+		}
+	}
+	for id := p.pages - 1; id > 0; id-- {
+		if !used[id] {
+			p.free = append(p.free, id)
+		}
+	}
+	return nil
+}
+
+// fail passes error to public method of DiskMap.
+func (p *pager[K, V]) fail(err error) {
+	panic(diskError{err: err})
+}
+
+// node returns node stored in specified page.
+func (p *pager[K, V]) node(id uint64) *diskNode[K, V] {
+	if e, ok := p.index[id]; ok {
+		p.cache.MoveToFront(e)
+		return e.Value.(*diskNode[K, V])
+	}
+	n, err := p.read(id)
+	if err != nil {
+		p.fail(err)
+	}
+	p.cacheNode(n)
+	return n
+}
+
+// mutable returns node that can be modified in place.
+//
+// Committed node is copied to new page and reference to it is updated.
+func (p *pager[K, V]) mutable(ref *uint64) *diskNode[K, V] {
+	n := p.node(*ref)
+	if _, ok := p.fresh[n.id]; ok {
+		n.dirty = true
+		return n
+	}
+	c := &diskNode[K, V]{
+		keys:     slices.Clone(n.keys),
+		values:   slices.Clone(n.values),
+		sizes:    slices.Clone(n.sizes),
+		children: slices.Clone(n.children),
+	}
+	p.release(n)
+	p.alloc(c)
+	*ref = c.id
+	return c
+}
+
+// alloc assigns new page to node.
+func (p *pager[K, V]) alloc(n *diskNode[K, V]) {
+	if len(p.free) > 0 {
+		n.id = p.free[len(p.free)-1]
+		p.free = p.free[:len(p.free)-1]
+	} else {
+		n.id = p.pages
+		p.pages++
+	}
+	p.fresh[n.id] = struct{}{}
+	n.dirty = true
+	p.cacheNode(n)
+}
+
+// release releases page of node that is no longer used.
+func (p *pager[K, V]) release(n *diskNode[K, V]) {
+	if _, ok := p.fresh[n.id]; ok {
+		delete(p.fresh, n.id)
+		p.free = append(p.free, n.id)
+	} else {
+		p.pending = append(p.pending, n.id)
+	}
+	if e, ok := p.index[n.id]; ok && e.Value == n {
+		p.cache.Remove(e)
+		delete(p.index, n.id)
+	}
+	n.dirty = false
+}
+
+func (p *pager[K, V]) cacheNode(n *diskNode[K, V]) {
+	p.index[n.id] = p.cache.PushFront(n)
+}
+
+// trim evicts least recently used nodes until cache fits in its size.
+//
+// Nodes are evicted only between operations, so nodes that are used
+// by operation are never evicted.
+func (p *pager[K, V]) trim() {
+	for p.cache.Len() > p.cacheSize {
+		e := p.cache.Back()
+		n := e.Value.(*diskNode[K, V])
+		if n.dirty {
+			p.write(n)
+		}
+		p.cache.Remove(e)
+		delete(p.index, n.id)
+	}
+}
+
+// commit writes all modified nodes and header with specified state
+// of tree.
+func (p *pager[K, V]) commit(root uint64, height, len int) {
+	for e := p.cache.Front(); e != nil; e = e.Next() {
+		if n := e.Value.(*diskNode[K, V]); n.dirty {
+			p.write(n)
+		}
+	}
+	if err := p.file.Sync(); err != nil {
+		p.fail(err)
+	}
+	header := diskHeader{
+		seq:      p.header.seq + 1,
+		pageSize: p.pageSize,
+		root:     root,
+		height:   height,
+		len:      len,
+		pages:    p.pages,
+	}
+	if err := p.writeHeader(header); err != nil {
+		p.fail(err)
+	}
+	p.header = header
+	p.free = append(p.free, p.pending...)
+	p.pending = p.pending[:0]
+	clear(p.fresh)
+}
+
+// capacity returns maximal size of items and references to children
+// of node.
+func (p *pager[K, V]) capacity() int {
+	return p.pageSize - pageHeaderSize
+}
+
+// itemSize returns encoded size of item.
+func (p *pager[K, V]) itemSize(key K, value V) int {
+	p.buf = p.keyCodec.Append(p.buf[:0], key)
+	p.buf = p.valueCodec.Append(p.buf, value)
+	return len(p.buf)
+}
+
+// write encodes node and writes it to its page.
+func (p *pager[K, V]) write(n *diskNode[K, V]) {
+	buf := p.buf[:pageHeaderSize]
+	buf[4] = leafPage
+	if n.children != nil {
+		buf[4] = internalPage
+		for _, child := range n.children {
+			buf = binary.LittleEndian.AppendUint64(buf, child)
+		}
+	}
+	binary.LittleEndian.PutUint32(buf[5:], uint32(len(n.keys)))
+	for i := range n.keys {
+		buf = p.keyCodec.Append(buf, n.keys[i])
+		buf = p.valueCodec.Append(buf, n.values[i])
+	}
+	if len(buf) > p.pageSize {
+		p.fail(fmt.Errorf("page %d: node does not fit into page", n.id))
+	}
+	size := len(buf)
+	buf = buf[:p.pageSize]
+	clear(buf[size:])
+	binary.LittleEndian.PutUint32(buf, crc32.ChecksumIEEE(buf[4:]))
+	p.buf = buf
+	if _, err := p.file.WriteAt(buf, int64(n.id)*int64(p.pageSize)); err != nil {
+		p.fail(err)
+	}
+	n.dirty = false
+}
+
+// read reads and decodes node from page.
+func (p *pager[K, V]) read(id uint64) (*diskNode[K, V], error) {
+	buf := p.buf[:p.pageSize]
+	if _, err := p.file.ReadAt(buf, int64(id)*int64(p.pageSize)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrCorrupted
+		}
+		return nil, fmt.Errorf("page %d: %w", id, err)
+	}
+	n, err := p.decode(buf)
+	if err != nil {
+		return nil, fmt.Errorf("page %d: %w", id, err)
+	}
+	n.id = id
+	return n, nil
+}
+
+func (p *pager[K, V]) decode(buf []byte) (*diskNode[K, V], error) {
+	if binary.LittleEndian.Uint32(buf) != crc32.ChecksumIEEE(buf[4:]) {
+		return nil, ErrCorrupted
+	}
+	count := int(binary.LittleEndian.Uint32(buf[5:]))
+	if count > len(buf) {
+		return nil, ErrCorrupted
+	}
+	n := diskNode[K, V]{
+		keys:   make([]K, count),
+		values: make([]V, count),
+		sizes:  make([]int, count),
+	}
+	pos := pageHeaderSize
+	switch buf[4] {
+	case leafPage:
+	case internalPage:
+		if pos+8*(count+1) > len(buf) {
+			return nil, ErrCorrupted
+		}
+		n.children = make([]uint64, count+1)
+		for i := range n.children {
+			n.children[i] = binary.LittleEndian.Uint64(buf[pos:])
+			pos += 8
+		}
+	default:
+		return nil, ErrCorrupted
+	}
+	for i := 0; i < count; i++ {
+		start := pos
+		key, size, err := p.keyCodec.Decode(buf[pos:])
+		if err != nil {
+			return nil, err
+		}
+		pos += size
+		value, size, err := p.valueCodec.Decode(buf[pos:])
+		if err != nil {
+			return nil, err
+		}
+		pos += size
+		n.keys[i] = key
+		n.values[i] = value
+		n.sizes[i] = pos - start
+	}
+	return &n, nil
+}
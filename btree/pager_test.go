@@ -0,0 +1,64 @@
+package btree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPagerCache(t *testing.T) {
+	m := openTestDiskMap(t, filepath.Join(t.TempDir(), "map"))
+	defer func() { _ = m.Close() }()
+	p := m.(*diskMapImpl[int, int]).pager
+	for i := 0; i < 10000; i++ {
+		m.Set(i, i)
+		if v := p.cache.Len(); v > p.cacheSize {
+			t.Fatalf("Expected at most %d cached pages, got %d", p.cacheSize, v)
+		}
+		if v := len(p.index); v != p.cache.Len() {
+			t.Fatalf("Expected %d indexed pages, got %d", p.cache.Len(), v)
+		}
+	}
+	for i := 0; i < 10000; i += 100 {
+		if v, ok := m.Get(i); !ok || v != i {
+			t.Fatalf("Expected value = %d, got %d", i, v)
+		}
+	}
+}
+
+func TestPagerHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	m := openTestDiskMap(t, path)
+	for i := 0; i < 100; i++ {
+		m.Set(i, i)
+	}
+	if err := m.Sync(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	for i := 100; i < 200; i++ {
+		m.Set(i, i)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	seq := m.(*diskMapImpl[int, int]).pager.header.seq
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	// Emulate torn write of the last header.
+	offset := int64(seq%2)*diskSlotSize + 20
+	if _, err := file.WriteAt([]byte{0xff}, offset); err != nil {
+		t.Fatal("Error:", err)
+	}
+	_ = file.Close()
+	m = openTestDiskMap(t, path)
+	defer func() { _ = m.Close() }()
+	testCheckDiskMap(t, m)
+	if v := m.Len(); v != 100 {
+		t.Fatalf("Expected len = %d, got %d", 100, v)
+	}
+	if v := m.(*diskMapImpl[int, int]).pager.header.seq; v != seq-1 {
+		t.Fatalf("Expected seq = %d, got %d", seq-1, v)
+	}
+}
@@ -0,0 +1,547 @@
+package btree
+
+import "iter"
+
+// PlusMap represents map implementation using B+Tree.
+//
+// Unlike Map, values are stored only in leaves and leaves are linked
+// with each other, so iteration over items does not need to climb
+// back to root. Internal nodes contain only separator keys.
+type PlusMap[K, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V)
+	Delete(key K)
+	Len() int
+	Iter() MapIter[K, V]
+	// All returns iterator over all items in ascending order.
+	//
+	// Map can be modified during iteration: after each modification
+	// iteration continues from the first item with greater key.
+	All() iter.Seq2[K, V]
+	// Backward returns iterator over all items in descending order.
+	//
+	// Map can be modified during iteration: after each modification
+	// iteration continues from the last item with smaller key.
+	Backward() iter.Seq2[K, V]
+	// Range returns iterator over items with lo <= key < hi in
+	// ascending order.
+	Range(lo, hi K) iter.Seq2[K, V]
+	// RangeFrom returns iterator over items with key >= lo in
+	// ascending order.
+	RangeFrom(lo K) iter.Seq2[K, V]
+	// Keys returns iterator over all keys in ascending order.
+	Keys() iter.Seq[K]
+	// Values returns iterator over all values in ascending order
+	// of keys.
+	Values() iter.Seq[V]
+}
+
+func NewPlusMap[K, V any](less func(K, K) bool) PlusMap[K, V] {
+	return NewPlusMapWithOptions[K, V](less, MapOptions{})
+}
+
+// NewPlusMapWithOptions creates new B+Tree map with specified options.
+//
+// Degree limits both amount of items in leaves and amount of
+// separator keys in internal nodes.
+func NewPlusMapWithOptions[K, V any](less func(K, K) bool, options MapOptions) PlusMap[K, V] {
+	maxLen := mapMaxLen[K, V](options)
+	return &plusMapImpl[K, V]{
+		less:   less,
+		maxLen: maxLen,
+		minLen: maxLen / 2,
+	}
+}
+
+// plusNode represents node of B+Tree.
+//
+// Keys of leaf are keys of items. Keys of internal node are separators:
+// all keys of children[i] are less than keys[i] and all keys of
+// children[i+1] are greater than or equal to keys[i].
+//
+// Node has space for one extra item, so it can be split after
+// insertion.
+type plusNode[K, V any] struct {
+	keys     []K
+	values   []V
+	children []*plusNode[K, V]
+	// next and prev are siblings of leaf.
+	next *plusNode[K, V]
+	prev *plusNode[K, V]
+	len  int
+}
+
+type plusMapImpl[K, V any] struct {
+	root *plusNode[K, V]
+	less func(K, K) bool
+	len  int
+	// version is changed on each modification of map.
+	version uint64
+	maxLen  int
+	minLen  int
+}
+
+func (m *plusMapImpl[K, V]) Get(key K) (V, bool) {
+	var empty V
+	if m.root == nil {
+		return empty, false
+	}
+	n := m.findLeaf(key)
+	i, ok := m.searchLeaf(n, key)
+	if !ok {
+		return empty, false
+	}
+	return n.values[i], true
+}
+
+func (m *plusMapImpl[K, V]) Set(key K, value V) {
+	m.version++
+	if m.root == nil {
+		m.root = m.newLeaf()
+	}
+	sep, right := m.setNode(m.root, key, value)
+	if right != nil {
+		root := m.newInternal()
+		root.len = 1
+		root.keys[0] = sep
+		root.children[0] = m.root
+		root.children[1] = right
+		m.root = root
+	}
+}
+
+func (m *plusMapImpl[K, V]) Delete(key K) {
+	if m.root == nil {
+		return
+	}
+	m.version++
+	m.deleteNode(m.root, key)
+	if m.root.children != nil && m.root.len == 0 {
+		m.root = m.root.children[0]
+	}
+	if m.len == 0 {
+		m.root = nil
+	}
+}
+
+func (m *plusMapImpl[K, V]) Len() int {
+	return m.len
+}
+
+func (m *plusMapImpl[K, V]) Iter() MapIter[K, V] {
+	return &plusIter[K, V]{m: m}
+}
+
+func (m *plusMapImpl[K, V]) newLeaf() *plusNode[K, V] {
+	return &plusNode[K, V]{
+		keys:   make([]K, m.maxLen+1),
+		values: make([]V, m.maxLen+1),
+	}
+}
+
+func (m *plusMapImpl[K, V]) newInternal() *plusNode[K, V] {
+	return &plusNode[K, V]{
+		keys:     make([]K, m.maxLen+1),
+		children: make([]*plusNode[K, V], m.maxLen+2),
+	}
+}
+
+// searchLeaf returns `pos` that `keys[pos] >= key` and flag that `keys[pos] == key`.
+func (m *plusMapImpl[K, V]) searchLeaf(n *plusNode[K, V], key K) (int, bool) {
+	low, high := 0, n.len
+	for low < high {
+		mid := (low + high) / 2
+		if m.less(n.keys[mid], key) {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	return low, low < n.len && !m.less(key, n.keys[low])
+}
+
+// searchChild returns index of child that can contain key.
+func (m *plusMapImpl[K, V]) searchChild(n *plusNode[K, V], key K) int {
+	low, high := 0, n.len
+	for low < high {
+		mid := (low + high) / 2
+		if m.less(key, n.keys[mid]) {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return low
+}
+
+// findLeaf returns leaf that can contain key.
+func (m *plusMapImpl[K, V]) findLeaf(key K) *plusNode[K, V] {
+	n := m.root
+	for n.children != nil {
+		n = n.children[m.searchChild(n, key)]
+	}
+	return n
+}
+
+// setNode sets value by key in subtree of node.
+//
+// If node is split, then separator and new right node are returned.
+func (m *plusMapImpl[K, V]) setNode(n *plusNode[K, V], key K, value V) (K, *plusNode[K, V]) {
+	var empty K
+	if n.children == nil {
+		i, ok := m.searchLeaf(n, key)
+		if ok {
+			n.keys[i] = key
+			n.values[i] = value
+			return empty, nil
+		}
+		copy(n.keys[i+1:], n.keys[i:n.len])
+		copy(n.values[i+1:], n.values[i:n.len])
+		n.keys[i] = key
+		n.values[i] = value
+		n.len++
+		m.len++
+		if n.len <= m.maxLen {
+			return empty, nil
+		}
+		return m.splitLeaf(n)
+	}
+	i := m.searchChild(n, key)
+	sep, right := m.setNode(n.children[i], key, value)
+	if right == nil {
+		return empty, nil
+	}
+	copy(n.keys[i+1:], n.keys[i:n.len])
+	copy(n.children[i+2:], n.children[i+1:n.len+1])
+	n.keys[i] = sep
+	n.children[i+1] = right
+	n.len++
+	if n.len <= m.maxLen {
+		return empty, nil
+	}
+	return m.splitInternal(n)
+}
+
+func (m *plusMapImpl[K, V]) splitLeaf(n *plusNode[K, V]) (K, *plusNode[K, V]) {
+	i := n.len / 2
+	right := m.newLeaf()
+	right.len = n.len - i
+	copy(right.keys, n.keys[i:n.len])
+	copy(right.values, n.values[i:n.len])
+	clear(n.keys[i:n.len])
+	clear(n.values[i:n.len])
+	n.len = i
+	right.next = n.next
+	right.prev = n
+	if n.next != nil {
+		n.next.prev = right
+	}
+	n.next = right
+	return right.keys[0], right
+}
+
+func (m *plusMapImpl[K, V]) splitInternal(n *plusNode[K, V]) (K, *plusNode[K, V]) {
+	i := n.len / 2
+	sep := n.keys[i]
+	right := m.newInternal()
+	right.len = n.len - i - 1
+	copy(right.keys, n.keys[i+1:n.len])
+	copy(right.children, n.children[i+1:n.len+1])
+	clear(n.keys[i:n.len])
+	clear(n.children[i+1 : n.len+1])
+	n.len = i
+	return sep, right
+}
+
+func (m *plusMapImpl[K, V]) deleteNode(n *plusNode[K, V], key K) bool {
+	if n.children == nil {
+		i, ok := m.searchLeaf(n, key)
+		if !ok {
+			return false
+		}
+		copy(n.keys[i:], n.keys[i+1:n.len])
+		copy(n.values[i:], n.values[i+1:n.len])
+		n.len--
+		var emptyKey K
+		var emptyValue V
+		n.keys[n.len] = emptyKey
+		n.values[n.len] = emptyValue
+		m.len--
+		return true
+	}
+	i := m.searchChild(n, key)
+	if !m.deleteNode(n.children[i], key) {
+		return false
+	}
+	if n.children[i].len < m.minLen {
+		m.rebalanceNode(n, i)
+	}
+	return true
+}
+
+func (m *plusMapImpl[K, V]) rebalanceNode(n *plusNode[K, V], i int) {
+	if i == n.len {
+		i--
+	}
+	left := n.children[i]
+	right := n.children[i+1]
+	if left.children == nil {
+		m.rebalanceLeaves(n, i, left, right)
+	} else {
+		m.rebalanceInternals(n, i, left, right)
+	}
+}
+
+func (m *plusMapImpl[K, V]) rebalanceLeaves(n *plusNode[K, V], i int, left, right *plusNode[K, V]) {
+	if left.len+right.len <= m.maxLen {
+		copy(left.keys[left.len:], right.keys[:right.len])
+		copy(left.values[left.len:], right.values[:right.len])
+		left.len += right.len
+		left.next = right.next
+		if right.next != nil {
+			right.next.prev = left
+		}
+		m.removeChild(n, i)
+	} else if left.len > right.len {
+		copy(right.keys[1:], right.keys[:right.len])
+		copy(right.values[1:], right.values[:right.len])
+		right.len++
+		left.len--
+		right.keys[0] = left.keys[left.len]
+		right.values[0] = left.values[left.len]
+		var emptyKey K
+		var emptyValue V
+		left.keys[left.len] = emptyKey
+		left.values[left.len] = emptyValue
+		n.keys[i] = right.keys[0]
+	} else {
+		left.keys[left.len] = right.keys[0]
+		left.values[left.len] = right.values[0]
+		left.len++
+		copy(right.keys, right.keys[1:right.len])
+		copy(right.values, right.values[1:right.len])
+		right.len--
+		var emptyKey K
+		var emptyValue V
+		right.keys[right.len] = emptyKey
+		right.values[right.len] = emptyValue
+		n.keys[i] = right.keys[0]
+	}
+}
+
+func (m *plusMapImpl[K, V]) rebalanceInternals(n *plusNode[K, V], i int, left, right *plusNode[K, V]) {
+	if left.len+right.len < m.maxLen {
+		left.keys[left.len] = n.keys[i]
+		copy(left.keys[left.len+1:], right.keys[:right.len])
+		copy(left.children[left.len+1:], right.children[:right.len+1])
+		left.len += right.len + 1
+		m.removeChild(n, i)
+	} else if left.len > right.len {
+		copy(right.keys[1:], right.keys[:right.len])
+		copy(right.children[1:], right.children[:right.len+1])
+		right.keys[0] = n.keys[i]
+		right.children[0] = left.children[left.len]
+		right.len++
+		left.len--
+		n.keys[i] = left.keys[left.len]
+		var emptyKey K
+		left.keys[left.len] = emptyKey
+		left.children[left.len+1] = nil
+	} else {
+		left.keys[left.len] = n.keys[i]
+		left.children[left.len+1] = right.children[0]
+		left.len++
+		n.keys[i] = right.keys[0]
+		copy(right.keys, right.keys[1:right.len])
+		copy(right.children, right.children[1:right.len+1])
+		right.len--
+		var emptyKey K
+		right.keys[right.len] = emptyKey
+		right.children[right.len+1] = nil
+	}
+}
+
+// removeChild removes separator keys[i] and child children[i+1].
+func (m *plusMapImpl[K, V]) removeChild(n *plusNode[K, V], i int) {
+	copy(n.keys[i:], n.keys[i+1:n.len])
+	copy(n.children[i+1:], n.children[i+2:n.len+1])
+	n.len--
+	var emptyKey K
+	n.keys[n.len] = emptyKey
+	n.children[n.len+1] = nil
+}
+
+// plusIter represents iterator over B+Tree map.
+//
+// Like mapIter, iterator panics after modification of map that is
+// not made through iterator.
+type plusIter[K, V any] struct {
+	m *plusMapImpl[K, V]
+	n *plusNode[K, V]
+	i int
+	// version is version of map at the moment of positioning.
+	version uint64
+}
+
+// move moves iterator to item i of leaf n, or to the next leaf
+// if i is out of leaf.
+func (m *plusIter[K, V]) move(n *plusNode[K, V], i int) bool {
+	if i >= n.len {
+		n, i = n.next, 0
+	} else if i < 0 {
+		n = n.prev
+		if n != nil {
+			i = n.len - 1
+		}
+	}
+	m.n, m.i = n, i
+	m.version = m.m.version
+	return n != nil
+}
+
+func (m *plusIter[K, V]) First() bool {
+	if m.m.root == nil {
+		return false
+	}
+	n := m.m.root
+	for n.children != nil {
+		n = n.children[0]
+	}
+	return m.move(n, 0)
+}
+
+func (m *plusIter[K, V]) Last() bool {
+	if m.m.root == nil {
+		return false
+	}
+	n := m.m.root
+	for n.children != nil {
+		n = n.children[n.len]
+	}
+	return m.move(n, n.len-1)
+}
+
+func (m *plusIter[K, V]) Next() bool {
+	if m.n == nil {
+		return m.First()
+	}
+	m.check()
+	if m.i+1 < m.n.len {
+		m.i++
+		return true
+	}
+	return m.move(m.n, m.i+1)
+}
+
+func (m *plusIter[K, V]) Prev() bool {
+	if m.n == nil {
+		return m.Last()
+	}
+	m.check()
+	if m.i > 0 {
+		m.i--
+		return true
+	}
+	return m.move(m.n, m.i-1)
+}
+
+func (m *plusIter[K, V]) Seek(key K) bool {
+	if m.m.root == nil {
+		return false
+	}
+	n := m.m.findLeaf(key)
+	i, _ := m.m.searchLeaf(n, key)
+	return m.move(n, i)
+}
+
+func (m *plusIter[K, V]) SeekPrev(key K) bool {
+	if m.m.root == nil {
+		return false
+	}
+	n := m.m.findLeaf(key)
+	i, ok := m.m.searchLeaf(n, key)
+	if !ok {
+		i--
+	}
+	return m.move(n, i)
+}
+
+func (m *plusIter[K, V]) Key() K {
+	if m.n == nil {
+		var empty K
+		return empty
+	}
+	m.check()
+	return m.n.keys[m.i]
+}
+
+func (m *plusIter[K, V]) Value() V {
+	if m.n == nil {
+		var empty V
+		return empty
+	}
+	m.check()
+	return m.n.values[m.i]
+}
+
+func (m *plusIter[K, V]) SetValue(value V) {
+	m.check()
+	m.n.values[m.i] = value
+}
+
+func (m *plusIter[K, V]) Delete() bool {
+	if m.n == nil {
+		return false
+	}
+	m.check()
+	key := m.n.keys[m.i]
+	m.m.Delete(key)
+	// Key is already removed, so seek moves to the next item.
+	if !m.Seek(key) {
+		m.n = nil
+		return false
+	}
+	return true
+}
+
+func (m *plusIter[K, V]) check() {
+	checkVersion(m.version, m.m.version)
+}
+
+func (m *plusMapImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := plusIter[K, V]{m: m}
+		walk(&it, it.First(), false, m.less, &m.version, yield)
+	}
+}
+
+func (m *plusMapImpl[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := plusIter[K, V]{m: m}
+		walk(&it, it.Last(), true, m.less, &m.version, yield)
+	}
+}
+
+func (m *plusMapImpl[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := plusIter[K, V]{m: m}
+		walk(&it, it.Seek(lo), false, m.less, &m.version, func(key K, value V) bool {
+			return m.less(key, hi) && yield(key, value)
+		})
+	}
+}
+
+func (m *plusMapImpl[K, V]) RangeFrom(lo K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := plusIter[K, V]{m: m}
+		walk(&it, it.Seek(lo), false, m.less, &m.version, yield)
+	}
+}
+
+func (m *plusMapImpl[K, V]) Keys() iter.Seq[K] {
+	return keys(m.All())
+}
+
+func (m *plusMapImpl[K, V]) Values() iter.Seq[V] {
+	return values(m.All())
+}
@@ -0,0 +1,304 @@
+package btree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// testCheckPlusMap checks that all leaves have the same depth, all
+// nodes are filled at least by half, keys are sorted and leaves are
+// linked in order.
+func testCheckPlusMap(tb testing.TB, m PlusMap[int, int]) {
+	impl := m.(*plusMapImpl[int, int])
+	if impl.root == nil {
+		if impl.len != 0 {
+			tb.Fatalf("Expected len = %d, got %d", 0, impl.len)
+		}
+		return
+	}
+	depth := -1
+	count := 0
+	var leaves []*plusNode[int, int]
+	var check func(n *plusNode[int, int], level int, lo, hi *int)
+	check = func(n *plusNode[int, int], level int, lo, hi *int) {
+		if n.len > impl.maxLen || (n != impl.root && n.len < impl.minLen) {
+			tb.Fatalf("Invalid node len = %d", n.len)
+		}
+		for i := 0; i < n.len; i++ {
+			if (i > 0 && n.keys[i-1] >= n.keys[i]) ||
+				(lo != nil && n.keys[i] < *lo) ||
+				(hi != nil && n.keys[i] >= *hi) {
+				tb.Fatalf("Key %d is out of order", n.keys[i])
+			}
+		}
+		if n.children == nil {
+			if depth == -1 {
+				depth = level
+			} else if depth != level {
+				tb.Fatal("Tree is not balanced")
+			}
+			count += n.len
+			leaves = append(leaves, n)
+			return
+		}
+		for i := 0; i <= n.len; i++ {
+			clo, chi := lo, hi
+			if i > 0 {
+				clo = &n.keys[i-1]
+			}
+			if i < n.len {
+				chi = &n.keys[i]
+			}
+			check(n.children[i], level+1, clo, chi)
+		}
+	}
+	check(impl.root, 0, nil, nil)
+	if count != impl.len {
+		tb.Fatalf("Expected len = %d, got %d", count, impl.len)
+	}
+	for i, n := range leaves {
+		if i > 0 && n.prev != leaves[i-1] || i == 0 && n.prev != nil {
+			tb.Fatal("Invalid prev leaf")
+		}
+		if i+1 < len(leaves) && n.next != leaves[i+1] || i+1 == len(leaves) && n.next != nil {
+			tb.Fatal("Invalid next leaf")
+		}
+	}
+}
+
+func TestRandomPlusMap(t *testing.T) {
+	for _, degree := range []int{2, 3, 32} {
+		m := NewPlusMapWithOptions[int, int](intLess, MapOptions{Degree: degree})
+		rnd := rand.New(rand.NewSource(42))
+		n := 3000
+		p := rnd.Perm(n)
+		for i := 0; i < n; i++ {
+			m.Set(p[i], i)
+			if v := m.Len(); v != i+1 {
+				t.Fatalf("Expected len = %d, got %d", i+1, v)
+			}
+			if i%100 == 0 {
+				testCheckPlusMap(t, m)
+			}
+		}
+		testCheckPlusMap(t, m)
+		for i := 0; i < n; i++ {
+			if v, ok := m.Get(p[i]); !ok || v != i {
+				t.Fatalf("Expected value = %d, got %d", i, v)
+			}
+		}
+		if _, ok := m.Get(n); ok {
+			t.Fatalf("Key %d should not exist", n)
+		}
+		p = rnd.Perm(n)
+		for i := 0; i < n; i++ {
+			m.Delete(p[i])
+			if v := m.Len(); v != n-i-1 {
+				t.Fatalf("Expected len = %d, got %d", n-i-1, v)
+			}
+			if _, ok := m.Get(p[i]); ok {
+				t.Fatalf("Key %d should not exist", p[i])
+			}
+			if i%100 == 0 {
+				testCheckPlusMap(t, m)
+			}
+		}
+		testCheckPlusMap(t, m)
+	}
+}
+
+func TestPlusMapIterators(t *testing.T) {
+	m := NewPlusMapWithOptions[int, int](intLess, MapOptions{Degree: 3})
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(i*2, i)
+	}
+	if v := slices.Collect(m.Keys()); len(v) != n || !slices.IsSorted(v) {
+		t.Fatalf("Invalid keys: %v", v)
+	}
+	if v := slices.Collect(m.Values()); len(v) != n || v[n-1] != n-1 {
+		t.Fatalf("Invalid values: %v", v)
+	}
+	var backward []int
+	for k := range m.Backward() {
+		backward = append(backward, k)
+	}
+	slices.Reverse(backward)
+	if !slices.Equal(backward, slices.Collect(m.Keys())) {
+		t.Fatalf("Invalid keys: %v", backward)
+	}
+	var rng []int
+	for k := range m.Range(9, 20) {
+		rng = append(rng, k)
+	}
+	if !slices.Equal(rng, []int{10, 12, 14, 16, 18}) {
+		t.Fatalf("Invalid keys: %v", rng)
+	}
+	rng = nil
+	for k := range m.RangeFrom(2*n - 9) {
+		rng = append(rng, k)
+	}
+	if !slices.Equal(rng, []int{2*n - 8, 2*n - 6, 2*n - 4, 2*n - 2}) {
+		t.Fatalf("Invalid keys: %v", rng)
+	}
+	var keys []int
+	for k := range m.All() {
+		keys = append(keys, k)
+		m.Delete(k)
+		m.Delete(k + 2)
+	}
+	if len(keys) != n/2 || keys[1] != 4 || m.Len() != 0 {
+		t.Fatalf("Invalid keys: %v", keys)
+	}
+	testCheckPlusMap(t, m)
+}
+
+func TestPlusMapIterDelete(t *testing.T) {
+	m := NewPlusMapWithOptions[int, int](intLess, MapOptions{Degree: 3})
+	n := 3000
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	it := m.Iter()
+	ok := it.First()
+	for i := 0; i < n; i++ {
+		if !ok || it.Key() != i {
+			t.Fatalf("Expected key = %d", i)
+		}
+		if i%3 == 0 {
+			ok = it.Delete()
+		} else {
+			ok = it.Next()
+		}
+	}
+	if ok {
+		t.Fatal("Iter should be ended")
+	}
+	testCheckPlusMap(t, m)
+	if !it.Seek(10) {
+		t.Fatal("Unable to seek")
+	}
+	m.Delete(20)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Expected panic")
+		}
+	}()
+	it.Next()
+}
+
+func TestPlusMapIterDeleteUnpositioned(t *testing.T) {
+	m := NewPlusMap[int, int](intLess)
+	it := m.Iter()
+	if it.First() {
+		t.Fatal("Iter should be ended")
+	}
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	m.Set(0, 0)
+	m.Set(1, 1)
+	it = m.Iter()
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	for ok := it.First(); ok; ok = it.Next() {
+	}
+	if it.Key() != 0 || it.Value() != 0 {
+		t.Fatal("Unpositioned iterator should return empty item")
+	}
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	if v := m.Len(); v != 2 {
+		t.Fatalf("Expected len = %d, got %d", 2, v)
+	}
+	if _, ok := m.Get(0); !ok {
+		t.Fatalf("Key %d should exist", 0)
+	}
+}
+
+func BenchmarkBtreePlusMapSeqSet(b *testing.B) {
+	m := NewPlusMap[int, int](intLess)
+	for i := 0; i < b.N; i++ {
+		m.Set(i, i)
+	}
+}
+
+func BenchmarkBtreePlusMapSeqGet(b *testing.B) {
+	m := NewPlusMap[int, int](intLess)
+	for i := 0; i < b.N; i++ {
+		m.Set(i, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, ok := m.Get(i)
+		if !ok {
+			b.Fatalf("Unable to find key = %d", i)
+		}
+		if v != i {
+			b.Fatalf("Expected value = %d, got %d", i, v)
+		}
+	}
+}
+
+func BenchmarkBtreePlusMapScan(b *testing.B) {
+	m := NewPlusMap[int, int](intLess)
+	for i := 0; i < 1<<16; i++ {
+		m.Set(i, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for k, v := range m.All() {
+			if k != v {
+				b.Fatalf("Expected value = %d, got %d", k, v)
+			}
+		}
+	}
+}
+
+func BenchmarkBtreeSimpleIntMapScan(b *testing.B) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < 1<<16; i++ {
+		m.Set(i, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for k, v := range m.All() {
+			if k != v {
+				b.Fatalf("Expected value = %d, got %d", k, v)
+			}
+		}
+	}
+}
+
+func BenchmarkBtreePlusMapScanBackward(b *testing.B) {
+	m := NewPlusMap[int, int](intLess)
+	for i := 0; i < 1<<16; i++ {
+		m.Set(i, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for it := m.Iter(); it.Prev(); {
+			if it.Key() != it.Value() {
+				b.Fatalf("Expected value = %d, got %d", it.Key(), it.Value())
+			}
+		}
+	}
+}
+
+func BenchmarkBtreeSimpleIntMapScanBackward(b *testing.B) {
+	m := NewMap[int, int](intLess)
+	for i := 0; i < 1<<16; i++ {
+		m.Set(i, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for it := m.Iter(); it.Prev(); {
+			if it.Key() != it.Value() {
+				b.Fatalf("Expected value = %d, got %d", it.Key(), it.Value())
+			}
+		}
+	}
+}
@@ -0,0 +1,679 @@
+package btree
+
+import (
+	"iter"
+	"slices"
+	"strings"
+)
+
+// PrefixMap represents map with string or byte slice keys using B+Tree
+// with prefix compression.
+//
+// Each node stores common prefix of its keys only once and suffixes of
+// keys are stored in single buffer of node, so long keys with common
+// prefixes, like "tenant/bucket/object", use much less memory than in
+// Map. Separators of internal nodes are truncated to the shortest keys
+// that separate leaves.
+//
+// Keys are ordered lexicographically by bytes. Keys are not stored
+// as is, so Key of iterator returns new copy of key.
+type PrefixMap[K byteKey, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V)
+	Delete(key K)
+	Len() int
+	Iter() MapIter[K, V]
+	// All returns iterator over all items in ascending order.
+	//
+	// Map can be modified during iteration: after each modification
+	// iteration continues from the first item with greater key.
+	All() iter.Seq2[K, V]
+	// Backward returns iterator over all items in descending order.
+	//
+	// Map can be modified during iteration: after each modification
+	// iteration continues from the last item with smaller key.
+	Backward() iter.Seq2[K, V]
+	// Range returns iterator over items with lo <= key < hi in
+	// ascending order.
+	Range(lo, hi K) iter.Seq2[K, V]
+	// RangeFrom returns iterator over items with key >= lo in
+	// ascending order.
+	RangeFrom(lo K) iter.Seq2[K, V]
+	// Keys returns iterator over all keys in ascending order.
+	Keys() iter.Seq[K]
+	// Values returns iterator over all values in ascending order
+	// of keys.
+	Values() iter.Seq[V]
+}
+
+// byteKey represents types of keys of PrefixMap.
+type byteKey interface {
+	~string | ~[]byte
+}
+
+func NewPrefixMap[K byteKey, V any]() PrefixMap[K, V] {
+	return NewPrefixMapWithOptions[K, V](MapOptions{})
+}
+
+// NewPrefixMapWithOptions creates new prefix-compressed map with
+// specified options.
+//
+// Degree limits both amount of items in leaves and amount of
+// separator keys in internal nodes.
+func NewPrefixMapWithOptions[K byteKey, V any](options MapOptions) PrefixMap[K, V] {
+	maxLen := mapMaxLen[K, V](options)
+	return &prefixMapImpl[K, V]{
+		maxLen: maxLen,
+		minLen: maxLen / 2,
+	}
+}
+
+// prefixKeys represents sorted keys of node with common prefix.
+//
+// Prefix is kept equal to the longest common prefix of keys, so
+// it is extended when the first or the last key is removed.
+type prefixKeys struct {
+	prefix string
+	// data contains concatenated suffixes of keys.
+	data []byte
+	// ends contains end offsets of suffixes in data.
+	ends []uint32
+}
+
+func (k *prefixKeys) len() int {
+	return len(k.ends)
+}
+
+func (k *prefixKeys) begin(i int) uint32 {
+	if i == 0 {
+		return 0
+	}
+	return k.ends[i-1]
+}
+
+func (k *prefixKeys) suffix(i int) []byte {
+	return k.data[k.begin(i):k.ends[i]]
+}
+
+// key returns full key i.
+func (k *prefixKeys) key(i int) string {
+	return k.prefix + string(k.suffix(i))
+}
+
+// searchPrefixKeys returns `pos` that `key(pos) >= key` and flag that `key(pos) == key`.
+func searchPrefixKeys[K byteKey](k *prefixKeys, key K) (int, bool) {
+	n := min(len(key), len(k.prefix))
+	if head := string(key[:n]); head != k.prefix {
+		// Key does not start with prefix, so it is either less or
+		// greater than all keys.
+		if head < k.prefix {
+			return 0, false
+		}
+		return k.len(), false
+	}
+	rest := key[n:]
+	low, high := 0, k.len()
+	for low < high {
+		mid := (low + high) / 2
+		if string(k.suffix(mid)) < string(rest) {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	return low, low < k.len() && string(k.suffix(low)) == string(rest)
+}
+
+// insert inserts key at position i.
+//
+// If key does not start with prefix, then prefix is shortened.
+func (k *prefixKeys) insert(i int, key string) {
+	if k.len() == 0 {
+		k.prefix = strings.Clone(key)
+		k.data = k.data[:0]
+	} else if !strings.HasPrefix(key, k.prefix) {
+		k.shrink(commonPrefixLen(k.prefix, key))
+	}
+	suffix := key[len(k.prefix):]
+	begin := k.begin(i)
+	size := len(k.data)
+	k.data = slices.Grow(k.data, len(suffix))[:size+len(suffix)]
+	copy(k.data[int(begin)+len(suffix):], k.data[begin:size])
+	copy(k.data[begin:], suffix)
+	k.ends = slices.Insert(k.ends, i, begin)
+	for j := i; j < len(k.ends); j++ {
+		k.ends[j] += uint32(len(suffix))
+	}
+}
+
+// delete removes key i.
+func (k *prefixKeys) delete(i int) {
+	begin, end := k.begin(i), k.ends[i]
+	k.data = slices.Delete(k.data, int(begin), int(end))
+	k.ends = slices.Delete(k.ends, i, i+1)
+	for j := i; j < len(k.ends); j++ {
+		k.ends[j] -= end - begin
+	}
+	if i == 0 || i == k.len() {
+		k.extend()
+	}
+}
+
+// set replaces key i with key that has the same position in order.
+func (k *prefixKeys) set(i int, key string) {
+	k.delete(i)
+	k.insert(i, key)
+}
+
+// shrink shortens prefix to n bytes and prepends removed part
+// of prefix to suffixes.
+func (k *prefixKeys) shrink(n int) {
+	removed := k.prefix[n:]
+	data := make([]byte, 0, len(k.data)+len(removed)*k.len())
+	begin := uint32(0)
+	for i, end := range k.ends {
+		data = append(data, removed...)
+		data = append(data, k.data[begin:end]...)
+		begin = end
+		k.ends[i] = uint32(len(data))
+	}
+	k.prefix = k.prefix[:n]
+	k.data = data
+}
+
+// extend extends prefix to the longest common prefix of keys and
+// removes it from suffixes.
+func (k *prefixKeys) extend() {
+	if k.len() == 0 {
+		return
+	}
+	n := commonPrefixLen(k.suffix(0), k.suffix(k.len()-1))
+	if n == 0 {
+		return
+	}
+	k.prefix += string(k.suffix(0)[:n])
+	size, begin := 0, uint32(0)
+	for i, end := range k.ends {
+		size += copy(k.data[size:], k.data[int(begin)+n:end])
+		begin = end
+		k.ends[i] = uint32(size)
+	}
+	k.data = k.data[:size]
+}
+
+// slice returns copy of keys from i to j with the longest common
+// prefix.
+func (k *prefixKeys) slice(i, j int) prefixKeys {
+	if i == j {
+		return prefixKeys{}
+	}
+	n := commonPrefixLen(k.suffix(i), k.suffix(j-1))
+	r := prefixKeys{
+		prefix: k.prefix + string(k.suffix(i)[:n]),
+		data:   make([]byte, 0, int(k.ends[j-1]-k.begin(i))-n*(j-i)),
+		ends:   make([]uint32, 0, j-i),
+	}
+	for l := i; l < j; l++ {
+		r.data = append(r.data, k.suffix(l)[n:]...)
+		r.ends = append(r.ends, uint32(len(r.data)))
+	}
+	return r
+}
+
+// merge appends keys of other that are greater than all keys.
+func (k *prefixKeys) merge(other *prefixKeys) {
+	if other.len() == 0 {
+		return
+	}
+	if k.len() == 0 {
+		*k = other.slice(0, other.len())
+		return
+	}
+	n := commonPrefixLen(k.prefix, other.prefix)
+	if n < len(k.prefix) {
+		k.shrink(n)
+	}
+	removed := other.prefix[n:]
+	for i := range other.ends {
+		k.data = append(k.data, removed...)
+		k.data = append(k.data, other.suffix(i)...)
+		k.ends = append(k.ends, uint32(len(k.data)))
+	}
+}
+
+func commonPrefixLen[T byteKey, U byteKey](a T, b U) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// separator returns the shortest key that is greater than left and
+// less than or equal to right.
+func separator(left, right string) string {
+	return right[:commonPrefixLen(left, right)+1]
+}
+
+// prefixNode represents node of prefix-compressed B+Tree.
+//
+// Keys of internal node are separators like in plusNode.
+type prefixNode[V any] struct {
+	keys     prefixKeys
+	values   []V
+	children []*prefixNode[V]
+	// next and prev are siblings of leaf.
+	next *prefixNode[V]
+	prev *prefixNode[V]
+}
+
+type prefixMapImpl[K byteKey, V any] struct {
+	root *prefixNode[V]
+	len  int
+	// version is changed on each modification of map.
+	version uint64
+	maxLen  int
+	minLen  int
+}
+
+func (m *prefixMapImpl[K, V]) Get(key K) (V, bool) {
+	var empty V
+	if m.root == nil {
+		return empty, false
+	}
+	n := m.findLeaf(key)
+	i, ok := searchPrefixKeys(&n.keys, key)
+	if !ok {
+		return empty, false
+	}
+	return n.values[i], true
+}
+
+func (m *prefixMapImpl[K, V]) Set(key K, value V) {
+	m.version++
+	if m.root == nil {
+		m.root = &prefixNode[V]{}
+	}
+	sep, right := m.setNode(m.root, string(key), value)
+	if right != nil {
+		root := prefixNode[V]{
+			children: []*prefixNode[V]{m.root, right},
+		}
+		root.keys.insert(0, sep)
+		m.root = &root
+	}
+}
+
+func (m *prefixMapImpl[K, V]) Delete(key K) {
+	if m.root == nil {
+		return
+	}
+	m.version++
+	m.deleteNode(m.root, key)
+	if m.root.children != nil && m.root.keys.len() == 0 {
+		m.root = m.root.children[0]
+	}
+	if m.len == 0 {
+		m.root = nil
+	}
+}
+
+func (m *prefixMapImpl[K, V]) Len() int {
+	return m.len
+}
+
+func (m *prefixMapImpl[K, V]) Iter() MapIter[K, V] {
+	return &prefixIter[K, V]{m: m}
+}
+
+func (m *prefixMapImpl[K, V]) less(a, b K) bool {
+	return string(a) < string(b)
+}
+
+// findLeaf returns leaf that can contain key.
+func (m *prefixMapImpl[K, V]) findLeaf(key K) *prefixNode[V] {
+	n := m.root
+	for n.children != nil {
+		n = n.children[searchPrefixChild(n, key)]
+	}
+	return n
+}
+
+// searchPrefixChild returns index of child that can contain key.
+func searchPrefixChild[K byteKey, V any](n *prefixNode[V], key K) int {
+	i, ok := searchPrefixKeys(&n.keys, key)
+	if ok {
+		i++
+	}
+	return i
+}
+
+// setNode sets value by key in subtree of node.
+//
+// If node is split, then separator and new right node are returned.
+func (m *prefixMapImpl[K, V]) setNode(n *prefixNode[V], key string, value V) (string, *prefixNode[V]) {
+	if n.children == nil {
+		i, ok := searchPrefixKeys(&n.keys, key)
+		if ok {
+			n.values[i] = value
+			return "", nil
+		}
+		n.keys.insert(i, key)
+		n.values = slices.Insert(n.values, i, value)
+		m.len++
+		if n.keys.len() <= m.maxLen {
+			return "", nil
+		}
+		return m.splitLeaf(n)
+	}
+	i := searchPrefixChild(n, key)
+	sep, right := m.setNode(n.children[i], key, value)
+	if right == nil {
+		return "", nil
+	}
+	n.keys.insert(i, sep)
+	n.children = slices.Insert(n.children, i+1, right)
+	if n.keys.len() <= m.maxLen {
+		return "", nil
+	}
+	return m.splitInternal(n)
+}
+
+// splitLeaf moves the second half of leaf to new right leaf and
+// returns the shortest separator of leaves.
+func (m *prefixMapImpl[K, V]) splitLeaf(n *prefixNode[V]) (string, *prefixNode[V]) {
+	i := n.keys.len() / 2
+	sep := separator(n.keys.key(i-1), n.keys.key(i))
+	right := prefixNode[V]{
+		keys:   n.keys.slice(i, n.keys.len()),
+		values: slices.Clone(n.values[i:]),
+		next:   n.next,
+		prev:   n,
+	}
+	n.keys = n.keys.slice(0, i)
+	clear(n.values[i:])
+	n.values = n.values[:i]
+	if n.next != nil {
+		n.next.prev = &right
+	}
+	n.next = &right
+	return sep, &right
+}
+
+func (m *prefixMapImpl[K, V]) splitInternal(n *prefixNode[V]) (string, *prefixNode[V]) {
+	i := n.keys.len() / 2
+	sep := n.keys.key(i)
+	right := prefixNode[V]{
+		keys:     n.keys.slice(i+1, n.keys.len()),
+		children: slices.Clone(n.children[i+1:]),
+	}
+	n.keys = n.keys.slice(0, i)
+	clear(n.children[i+1:])
+	n.children = n.children[:i+1]
+	return sep, &right
+}
+
+func (m *prefixMapImpl[K, V]) deleteNode(n *prefixNode[V], key K) bool {
+	if n.children == nil {
+		i, ok := searchPrefixKeys(&n.keys, key)
+		if !ok {
+			return false
+		}
+		n.keys.delete(i)
+		n.values = slices.Delete(n.values, i, i+1)
+		m.len--
+		return true
+	}
+	i := searchPrefixChild(n, key)
+	if !m.deleteNode(n.children[i], key) {
+		return false
+	}
+	if n.children[i].keys.len() < m.minLen {
+		m.rebalanceNode(n, i)
+	}
+	return true
+}
+
+func (m *prefixMapImpl[K, V]) rebalanceNode(n *prefixNode[V], i int) {
+	if i == n.keys.len() {
+		i--
+	}
+	left := n.children[i]
+	right := n.children[i+1]
+	if left.children == nil {
+		m.rebalanceLeaves(n, i, left, right)
+	} else {
+		m.rebalanceInternals(n, i, left, right)
+	}
+}
+
+func (m *prefixMapImpl[K, V]) rebalanceLeaves(n *prefixNode[V], i int, left, right *prefixNode[V]) {
+	if left.keys.len()+right.keys.len() <= m.maxLen {
+		left.keys.merge(&right.keys)
+		left.values = append(left.values, right.values...)
+		left.next = right.next
+		if right.next != nil {
+			right.next.prev = left
+		}
+		m.removeChild(n, i)
+	} else if left.keys.len() > right.keys.len() {
+		j := left.keys.len() - 1
+		key := left.keys.key(j)
+		right.keys.insert(0, key)
+		right.values = slices.Insert(right.values, 0, left.values[j])
+		left.keys.delete(j)
+		left.values = slices.Delete(left.values, j, j+1)
+		n.keys.set(i, separator(left.keys.key(j-1), key))
+	} else {
+		key := right.keys.key(0)
+		left.keys.insert(left.keys.len(), key)
+		left.values = append(left.values, right.values[0])
+		right.keys.delete(0)
+		right.values = slices.Delete(right.values, 0, 1)
+		n.keys.set(i, separator(key, right.keys.key(0)))
+	}
+}
+
+func (m *prefixMapImpl[K, V]) rebalanceInternals(n *prefixNode[V], i int, left, right *prefixNode[V]) {
+	if left.keys.len()+right.keys.len() < m.maxLen {
+		left.keys.insert(left.keys.len(), n.keys.key(i))
+		left.keys.merge(&right.keys)
+		left.children = append(left.children, right.children...)
+		m.removeChild(n, i)
+	} else if left.keys.len() > right.keys.len() {
+		j := left.keys.len() - 1
+		right.keys.insert(0, n.keys.key(i))
+		right.children = slices.Insert(right.children, 0, left.children[j+1])
+		n.keys.set(i, left.keys.key(j))
+		left.keys.delete(j)
+		left.children = slices.Delete(left.children, j+1, j+2)
+	} else {
+		left.keys.insert(left.keys.len(), n.keys.key(i))
+		left.children = append(left.children, right.children[0])
+		n.keys.set(i, right.keys.key(0))
+		right.keys.delete(0)
+		right.children = slices.Delete(right.children, 0, 1)
+	}
+}
+
+// removeChild removes separator keys[i] and child children[i+1].
+func (m *prefixMapImpl[K, V]) removeChild(n *prefixNode[V], i int) {
+	n.keys.delete(i)
+	n.children = slices.Delete(n.children, i+1, i+2)
+}
+
+// prefixIter represents iterator over prefix-compressed map.
+//
+// Like plusIter, iterator panics after modification of map that is
+// not made through iterator.
+type prefixIter[K byteKey, V any] struct {
+	m *prefixMapImpl[K, V]
+	n *prefixNode[V]
+	i int
+	// version is version of map at the moment of positioning.
+	version uint64
+}
+
+// move moves iterator to item i of leaf n, or to the next leaf
+// if i is out of leaf.
+func (m *prefixIter[K, V]) move(n *prefixNode[V], i int) bool {
+	if i >= n.keys.len() {
+		n, i = n.next, 0
+	} else if i < 0 {
+		n = n.prev
+		if n != nil {
+			i = n.keys.len() - 1
+		}
+	}
+	m.n, m.i = n, i
+	m.version = m.m.version
+	return n != nil
+}
+
+func (m *prefixIter[K, V]) First() bool {
+	if m.m.root == nil {
+		return false
+	}
+	n := m.m.root
+	for n.children != nil {
+		n = n.children[0]
+	}
+	return m.move(n, 0)
+}
+
+func (m *prefixIter[K, V]) Last() bool {
+	if m.m.root == nil {
+		return false
+	}
+	n := m.m.root
+	for n.children != nil {
+		n = n.children[n.keys.len()]
+	}
+	return m.move(n, n.keys.len()-1)
+}
+
+func (m *prefixIter[K, V]) Next() bool {
+	if m.n == nil {
+		return m.First()
+	}
+	m.check()
+	if m.i+1 < m.n.keys.len() {
+		m.i++
+		return true
+	}
+	return m.move(m.n, m.i+1)
+}
+
+func (m *prefixIter[K, V]) Prev() bool {
+	if m.n == nil {
+		return m.Last()
+	}
+	m.check()
+	if m.i > 0 {
+		m.i--
+		return true
+	}
+	return m.move(m.n, m.i-1)
+}
+
+func (m *prefixIter[K, V]) Seek(key K) bool {
+	if m.m.root == nil {
+		return false
+	}
+	n := m.m.findLeaf(key)
+	i, _ := searchPrefixKeys(&n.keys, key)
+	return m.move(n, i)
+}
+
+func (m *prefixIter[K, V]) SeekPrev(key K) bool {
+	if m.m.root == nil {
+		return false
+	}
+	n := m.m.findLeaf(key)
+	i, ok := searchPrefixKeys(&n.keys, key)
+	if !ok {
+		i--
+	}
+	return m.move(n, i)
+}
+
+func (m *prefixIter[K, V]) Key() K {
+	if m.n == nil {
+		var empty K
+		return empty
+	}
+	m.check()
+	return K(m.n.keys.key(m.i))
+}
+
+func (m *prefixIter[K, V]) Value() V {
+	if m.n == nil {
+		var empty V
+		return empty
+	}
+	m.check()
+	return m.n.values[m.i]
+}
+
+func (m *prefixIter[K, V]) SetValue(value V) {
+	m.check()
+	m.n.values[m.i] = value
+}
+
+func (m *prefixIter[K, V]) Delete() bool {
+	if m.n == nil {
+		return false
+	}
+	key := m.Key()
+	m.m.Delete(key)
+	// Key is already removed, so seek moves to the next item.
+	if !m.Seek(key) {
+		m.n = nil
+		return false
+	}
+	return true
+}
+
+func (m *prefixIter[K, V]) check() {
+	checkVersion(m.version, m.m.version)
+}
+
+func (m *prefixMapImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := prefixIter[K, V]{m: m}
+		walk(&it, it.First(), false, m.less, &m.version, yield)
+	}
+}
+
+func (m *prefixMapImpl[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := prefixIter[K, V]{m: m}
+		walk(&it, it.Last(), true, m.less, &m.version, yield)
+	}
+}
+
+func (m *prefixMapImpl[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := prefixIter[K, V]{m: m}
+		walk(&it, it.Seek(lo), false, m.less, &m.version, func(key K, value V) bool {
+			return m.less(key, hi) && yield(key, value)
+		})
+	}
+}
+
+func (m *prefixMapImpl[K, V]) RangeFrom(lo K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := prefixIter[K, V]{m: m}
+		walk(&it, it.Seek(lo), false, m.less, &m.version, yield)
+	}
+}
+
+func (m *prefixMapImpl[K, V]) Keys() iter.Seq[K] {
+	return keys(m.All())
+}
+
+func (m *prefixMapImpl[K, V]) Values() iter.Seq[V] {
+	return values(m.All())
+}
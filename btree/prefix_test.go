@@ -0,0 +1,467 @@
+package btree
+
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/udovin/algo/ordered"
+	"github.com/udovin/algo/ordered/orderedtest"
+)
+
+// testCheckPrefixMap checks that all leaves have the same depth, all
+// nodes are filled at least by half, keys are sorted, separators split
+// keys of children and leaves are linked in order.
+func testCheckPrefixMap[K byteKey](tb testing.TB, m PrefixMap[K, int]) {
+	impl := m.(*prefixMapImpl[K, int])
+	if impl.root == nil {
+		if impl.len != 0 {
+			tb.Fatalf("Expected len = %d, got %d", 0, impl.len)
+		}
+		return
+	}
+	depth := -1
+	count := 0
+	var leaves []*prefixNode[int]
+	var check func(n *prefixNode[int], level int, lo, hi *string)
+	check = func(n *prefixNode[int], level int, lo, hi *string) {
+		keys := &n.keys
+		if keys.len() > impl.maxLen || (n != impl.root && keys.len() < impl.minLen) {
+			tb.Fatalf("Invalid node len = %d", keys.len())
+		}
+		if keys.len() > 0 && int(keys.ends[keys.len()-1]) != len(keys.data) {
+			tb.Fatal("Invalid suffixes of keys")
+		}
+		if keys.len() > 0 && commonPrefixLen(keys.suffix(0), keys.suffix(keys.len()-1)) != 0 {
+			tb.Fatalf("Prefix %q is not the longest", keys.prefix)
+		}
+		for i := 0; i < keys.len(); i++ {
+			key := keys.key(i)
+			if (i > 0 && keys.key(i-1) >= key) ||
+				(lo != nil && key < *lo) ||
+				(hi != nil && key >= *hi) {
+				tb.Fatalf("Key %q is out of order", key)
+			}
+		}
+		if n.children == nil {
+			if depth == -1 {
+				depth = level
+			} else if depth != level {
+				tb.Fatal("Tree is not balanced")
+			}
+			if len(n.values) != keys.len() {
+				tb.Fatal("Invalid leaf")
+			}
+			count += keys.len()
+			leaves = append(leaves, n)
+			return
+		}
+		if len(n.children) != keys.len()+1 {
+			tb.Fatal("Invalid internal node")
+		}
+		for i, child := range n.children {
+			clo, chi := lo, hi
+			if i > 0 {
+				sep := keys.key(i - 1)
+				clo = &sep
+			}
+			if i < keys.len() {
+				sep := keys.key(i)
+				chi = &sep
+			}
+			check(child, level+1, clo, chi)
+		}
+	}
+	check(impl.root, 0, nil, nil)
+	if count != impl.len {
+		tb.Fatalf("Expected len = %d, got %d", count, impl.len)
+	}
+	for i, n := range leaves {
+		if i > 0 && n.prev != leaves[i-1] || i == 0 && n.prev != nil {
+			tb.Fatal("Invalid prev leaf")
+		}
+		if i+1 < len(leaves) && n.next != leaves[i+1] || i+1 == len(leaves) && n.next != nil {
+			tb.Fatal("Invalid next leaf")
+		}
+	}
+}
+
+// testPrefixKey returns hierarchical key with long common prefixes.
+func testPrefixKey(i int) string {
+	return fmt.Sprintf("tenant-%02d/bucket-%03d/objects/%08d", i%7, i%31, i)
+}
+
+// prefixIntMap represents adapter of PrefixMap with integer keys that
+// are encoded with common prefix and preserved order.
+type prefixIntMap struct {
+	m PrefixMap[string, int]
+}
+
+func encodePrefixIntKey(key int) string {
+	return "tenant/bucket/" + string(binary.BigEndian.AppendUint64(nil, uint64(key)^(1<<63)))
+}
+
+func decodePrefixIntKey(key string) int {
+	return int(binary.BigEndian.Uint64([]byte(key[len(key)-8:])) ^ (1 << 63))
+}
+
+func (m prefixIntMap) Get(key int) (int, bool) {
+	return m.m.Get(encodePrefixIntKey(key))
+}
+
+func (m prefixIntMap) Set(key, value int) {
+	m.m.Set(encodePrefixIntKey(key), value)
+}
+
+func (m prefixIntMap) Delete(key int) {
+	m.m.Delete(encodePrefixIntKey(key))
+}
+
+func (m prefixIntMap) Len() int {
+	return m.m.Len()
+}
+
+func (m prefixIntMap) All() iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for key, value := range m.m.All() {
+			if !yield(decodePrefixIntKey(key), value) {
+				return
+			}
+		}
+	}
+}
+
+func (m prefixIntMap) Iter() MapIter[int, int] {
+	return prefixIntIter{m.m.Iter()}
+}
+
+type prefixIntIter struct {
+	MapIter[string, int]
+}
+
+func (it prefixIntIter) Seek(key int) bool {
+	return it.MapIter.Seek(encodePrefixIntKey(key))
+}
+
+func (it prefixIntIter) SeekPrev(key int) bool {
+	return it.MapIter.SeekPrev(encodePrefixIntKey(key))
+}
+
+func (it prefixIntIter) Key() int {
+	return decodePrefixIntKey(it.MapIter.Key())
+}
+
+func TestOrderedPrefixMap(t *testing.T) {
+	orderedtest.TestMap(t, func() ordered.Map[int, int] {
+		return AsOrdered(prefixIntMap{NewPrefixMapWithOptions[string, int](MapOptions{Degree: 2})})
+	})
+}
+
+func TestRandomPrefixMap(t *testing.T) {
+	for _, degree := range []int{2, 3, 32} {
+		m := NewPrefixMapWithOptions[string, int](MapOptions{Degree: degree})
+		rnd := rand.New(rand.NewSource(42))
+		n := 3000
+		p := rnd.Perm(n)
+		for i := 0; i < n; i++ {
+			m.Set(testPrefixKey(p[i]), i)
+			if v := m.Len(); v != i+1 {
+				t.Fatalf("Expected len = %d, got %d", i+1, v)
+			}
+			if i%100 == 0 {
+				testCheckPrefixMap(t, m)
+			}
+		}
+		testCheckPrefixMap(t, m)
+		for i := 0; i < n; i++ {
+			if v, ok := m.Get(testPrefixKey(p[i])); !ok || v != i {
+				t.Fatalf("Expected value = %d, got %d", i, v)
+			}
+		}
+		for _, key := range []string{"", "t", "tenant-00", testPrefixKey(n), "tenant-99"} {
+			if _, ok := m.Get(key); ok {
+				t.Fatalf("Key %q should not exist", key)
+			}
+		}
+		keys := slices.Collect(m.Keys())
+		if len(keys) != n || !slices.IsSorted(keys) {
+			t.Fatal("Invalid order of keys")
+		}
+		p = rnd.Perm(n)
+		for i := 0; i < n; i++ {
+			m.Delete(testPrefixKey(p[i]))
+			if v := m.Len(); v != n-i-1 {
+				t.Fatalf("Expected len = %d, got %d", n-i-1, v)
+			}
+			if _, ok := m.Get(testPrefixKey(p[i])); ok {
+				t.Fatalf("Key %q should not exist", testPrefixKey(p[i]))
+			}
+			if i%100 == 0 {
+				testCheckPrefixMap(t, m)
+			}
+		}
+		testCheckPrefixMap(t, m)
+	}
+}
+
+func TestPrefixMapKeys(t *testing.T) {
+	m := NewPrefixMapWithOptions[[]byte, int](MapOptions{Degree: 2})
+	// Keys are prefixes of each other and differ by bytes that are
+	// less than, equal to and greater than bytes of other keys.
+	keys := []string{"", "a", "a/", "a/b", "a/b/c", "a/bb", "a/c", "ab", "b", "\x00", "\xff", "a\x00", "a\xff"}
+	for i, key := range keys {
+		m.Set([]byte(key), i)
+		testCheckPrefixMap(t, m)
+	}
+	slices.Sort(keys)
+	var result []string
+	for key := range m.Keys() {
+		result = append(result, string(key))
+	}
+	if !slices.Equal(result, keys) {
+		t.Fatalf("Expected %q, got %q", keys, result)
+	}
+	it := m.Iter()
+	if !it.Seek([]byte("a/b/")) || string(it.Key()) != "a/b/c" {
+		t.Fatalf("Expected key = %q, got %q", "a/b/c", it.Key())
+	}
+	// Key of iterator is a copy.
+	it.Key()[0] = 'x'
+	if !it.SeekPrev([]byte("a/b/")) || string(it.Key()) != "a/b" {
+		t.Fatalf("Expected key = %q, got %q", "a/b", it.Key())
+	}
+	for _, key := range keys {
+		m.Delete([]byte(key))
+		testCheckPrefixMap(t, m)
+	}
+}
+
+func TestPrefixMapIterDeleteUnpositioned(t *testing.T) {
+	m := NewPrefixMap[string, int]()
+	it := m.Iter()
+	if it.First() {
+		t.Fatal("Iter should be ended")
+	}
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	m.Set("a", 0)
+	m.Set("b", 1)
+	it = m.Iter()
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	for ok := it.First(); ok; ok = it.Next() {
+	}
+	if it.Key() != "" || it.Value() != 0 {
+		t.Fatal("Unpositioned iterator should return empty item")
+	}
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	if v := m.Len(); v != 2 {
+		t.Fatalf("Expected len = %d, got %d", 2, v)
+	}
+	if _, ok := m.Get("a"); !ok {
+		t.Fatalf("Key %q should exist", "a")
+	}
+}
+
+func TestPrefixMapCompression(t *testing.T) {
+	m := NewPrefixMapWithOptions[string, int](MapOptions{Degree: 8})
+	n := 10000
+	for i := 0; i < n; i++ {
+		m.Set(testPrefixKey(i), i)
+	}
+	checkSize := func() {
+		testCheckPrefixMap(t, m)
+		size, total := 0, 0
+		var check func(n *prefixNode[int])
+		check = func(n *prefixNode[int]) {
+			if n.children == nil {
+				size += len(n.keys.prefix) + len(n.keys.data)
+				for i := 0; i < n.keys.len(); i++ {
+					total += len(n.keys.key(i))
+				}
+				return
+			}
+			// Separators are truncated, so they are shorter than keys.
+			for i := 0; i < n.keys.len(); i++ {
+				if key := n.keys.key(i); len(key) >= len(testPrefixKey(0)) {
+					t.Fatalf("Separator %q is not truncated", key)
+				}
+			}
+			for _, child := range n.children {
+				check(child)
+			}
+		}
+		check(m.(*prefixMapImpl[string, int]).root)
+		if size*2 > total {
+			t.Fatalf("Expected size <= %d, got %d", total/2, size)
+		}
+	}
+	checkSize()
+	// Prefixes are kept after deletion of most keys and insertion
+	// of new keys.
+	rnd := rand.New(rand.NewSource(42))
+	for _, i := range rnd.Perm(n) {
+		if i%10 != 0 {
+			m.Delete(testPrefixKey(i))
+		}
+		if i%5 == 0 {
+			m.Set(testPrefixKey(n+i), i)
+		}
+	}
+	checkSize()
+}
+
+func TestPrefixMapIterators(t *testing.T) {
+	m := NewPrefixMapWithOptions[string, int](MapOptions{Degree: 3})
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(testPrefixKey(i*2), i)
+	}
+	var backward []string
+	for k := range m.Backward() {
+		backward = append(backward, k)
+	}
+	slices.Reverse(backward)
+	if !slices.Equal(backward, slices.Collect(m.Keys())) {
+		t.Fatalf("Invalid keys: %v", backward)
+	}
+	count := 0
+	for k := range m.Range("tenant-01/", "tenant-02/") {
+		if !strings.HasPrefix(k, "tenant-01/") {
+			t.Fatalf("Key %q is out of range", k)
+		}
+		count++
+	}
+	if expected := (n + 2) / 7; count != expected {
+		t.Fatalf("Expected %d items, got %d", expected, count)
+	}
+	var keys []string
+	for k := range m.All() {
+		keys = append(keys, k)
+		m.Delete(k)
+	}
+	if len(keys) != n || m.Len() != 0 {
+		t.Fatalf("Expected %d items, got %d", n, len(keys))
+	}
+	testCheckPrefixMap(t, m)
+}
+
+// testStringMap represents common methods of maps with string keys.
+type testStringMap interface {
+	Set(key string, value int)
+	Delete(key string)
+}
+
+// benchmarkMemory reports amount of heap bytes per key that are
+// retained by map with n keys.
+//
+// After insertion of n keys in ascending order, map is modified churn
+// times by deletion of random key and insertion of new random key.
+func benchmarkMemory(b *testing.B, n, churn int, newMap func() testStringMap) {
+	var before, after runtime.MemStats
+	for i := 0; i < b.N; i++ {
+		rnd := rand.New(rand.NewSource(42))
+		// Keys are chosen before measurement, so only memory of map is
+		// reported. Initial keys are even and new keys are odd.
+		live := make([]int, n)
+		for j := range live {
+			live[j] = 2 * j
+		}
+		added := rnd.Perm(n)
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		m := newMap()
+		for _, key := range live {
+			m.Set(testPrefixKey(key), 0)
+		}
+		for j := 0; j < churn; j++ {
+			k := rnd.Intn(n)
+			m.Delete(testPrefixKey(live[k]))
+			live[k] = 2*added[j%n] + 1
+			m.Set(testPrefixKey(live[k]), 0)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(m)
+		runtime.KeepAlive(live)
+		runtime.KeepAlive(added)
+	}
+	b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/float64(n), "B/key")
+}
+
+func newTestPrefixMap() testStringMap {
+	return NewPrefixMap[string, int]()
+}
+
+func newTestStringMap() testStringMap {
+	return NewMap[string, int](func(a, b string) bool { return a < b })
+}
+
+func BenchmarkBtreePrefixMapMemory(b *testing.B) {
+	benchmarkMemory(b, 1<<16, 0, newTestPrefixMap)
+}
+
+func BenchmarkBtreeStringMapMemory(b *testing.B) {
+	benchmarkMemory(b, 1<<16, 0, newTestStringMap)
+}
+
+func BenchmarkBtreePrefixMapChurnMemory(b *testing.B) {
+	benchmarkMemory(b, 1<<16, 1<<16, newTestPrefixMap)
+}
+
+func BenchmarkBtreeStringMapChurnMemory(b *testing.B) {
+	benchmarkMemory(b, 1<<16, 1<<16, newTestStringMap)
+}
+
+func BenchmarkBtreePrefixMapSeqSet(b *testing.B) {
+	m := NewPrefixMap[string, int]()
+	for i := 0; i < b.N; i++ {
+		m.Set(testPrefixKey(i), i)
+	}
+}
+
+func BenchmarkBtreeStringMapSeqSet(b *testing.B) {
+	m := NewMap[string, int](func(a, b string) bool { return a < b })
+	for i := 0; i < b.N; i++ {
+		m.Set(testPrefixKey(i), i)
+	}
+}
+
+func BenchmarkBtreePrefixMapGet(b *testing.B) {
+	m := NewPrefixMap[string, int]()
+	keys := make([]string, 1<<16)
+	for i := range keys {
+		keys[i] = testPrefixKey(i)
+		m.Set(keys[i], i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := m.Get(keys[i&(1<<16-1)]); !ok {
+			b.Fatalf("Unable to find key = %q", keys[i&(1<<16-1)])
+		}
+	}
+}
+
+func BenchmarkBtreeStringMapGet(b *testing.B) {
+	m := NewMap[string, int](func(a, b string) bool { return a < b })
+	keys := make([]string, 1<<16)
+	for i := range keys {
+		keys[i] = testPrefixKey(i)
+		m.Set(keys[i], i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := m.Get(keys[i&(1<<16-1)]); !ok {
+			b.Fatalf("Unable to find key = %q", keys[i&(1<<16-1)])
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package btree
+
+import "iter"
+
+// SetIter represents iterator over set.
+type SetIter[K any] interface {
+	// Next moves iterator forward.
+	Next() bool
+	// Prev moves iterator backward.
+	Prev() bool
+	// First moves iterator to smallest key, or returns false if set
+	// is empty.
+	First() bool
+	// Last moves iterator to largest key, or returns false if set
+	// is empty.
+	Last() bool
+	// Seek moves iterator to smallest key >= key, or returns false
+	// if there is no such key.
+	Seek(key K) bool
+	// SeekPrev moves iterator to largest key <= key, or returns false
+	// if there is no such key.
+	SeekPrev(key K) bool
+	// Key returns current key.
+	Key() K
+	// Delete removes current key from set and moves iterator to
+	// next key, or returns false if there is no such key.
+	Delete() bool
+}
+
+// Set represents set implementation using B-Tree.
+//
+// Set shares implementation with Map but does not store values.
+type Set[K any] interface {
+	// Add adds key to set and returns false if key already exists.
+	Add(key K) bool
+	// Has returns true if set contains key.
+	Has(key K) bool
+	// Remove removes key from set and returns false if there is
+	// no such key.
+	Remove(key K) bool
+	Len() int
+	Iter() SetIter[K]
+	// All returns iterator over all keys in ascending order.
+	All() iter.Seq[K]
+	// Backward returns iterator over all keys in descending order.
+	Backward() iter.Seq[K]
+	// Range returns iterator over keys with lo <= key < hi in
+	// ascending order.
+	Range(lo, hi K) iter.Seq[K]
+	// RangeFrom returns iterator over keys with key >= lo in
+	// ascending order.
+	RangeFrom(lo K) iter.Seq[K]
+	// Union adds all keys of other set.
+	Union(other Set[K])
+	// Intersection removes keys that are missing in other set.
+	Intersection(other Set[K])
+	// Difference removes keys that are present in other set.
+	Difference(other Set[K])
+	// SymmetricDifference removes keys that are present in other set
+	// and adds keys of other set that are missing.
+	SymmetricDifference(other Set[K])
+	// Clone returns copy of set in O(1) time.
+	Clone() Set[K]
+}
+
+func NewSet[K any](less func(K, K) bool) Set[K] {
+	return NewSetWithOptions[K](less, MapOptions{})
+}
+
+// NewSetWithOptions creates new set with specified options.
+func NewSetWithOptions[K any](less func(K, K) bool, options MapOptions) Set[K] {
+	return &setImpl[K]{m: newMapImpl[K, struct{}](less, options)}
+}
+
+// setImpl represents set as map with empty values.
+//
+// Values of empty type have zero size, so nodes do not use memory
+// for values.
+type setImpl[K any] struct {
+	m mapImpl[K, struct{}]
+}
+
+func (s *setImpl[K]) Add(key K) bool {
+	added := false
+	s.m.compute(key, func(_ struct{}, exists bool) (struct{}, bool) {
+		added = !exists
+		return struct{}{}, true
+	}, false)
+	return added
+}
+
+func (s *setImpl[K]) Has(key K) bool {
+	_, ok := s.m.Get(key)
+	return ok
+}
+
+func (s *setImpl[K]) Remove(key K) bool {
+	if s.m.root == nil {
+		return false
+	}
+	if !s.m.deleteNode(&s.m.root, key) {
+		return false
+	}
+	s.m.version++
+	s.m.shrinkRoot()
+	return true
+}
+
+func (s *setImpl[K]) Len() int {
+	return s.m.len
+}
+
+func (s *setImpl[K]) Iter() SetIter[K] {
+	return &mapIter[K, struct{}]{m: &s.m}
+}
+
+func (s *setImpl[K]) All() iter.Seq[K] {
+	return setKeys(s.m.All())
+}
+
+func (s *setImpl[K]) Backward() iter.Seq[K] {
+	return setKeys(s.m.Backward())
+}
+
+func (s *setImpl[K]) Range(lo, hi K) iter.Seq[K] {
+	return setKeys(s.m.Range(lo, hi))
+}
+
+func (s *setImpl[K]) RangeFrom(lo K) iter.Seq[K] {
+	return setKeys(s.m.RangeFrom(lo))
+}
+
+func (s *setImpl[K]) Union(other Set[K]) {
+	s.m.Union(&other.(*setImpl[K]).m, setResolve[K])
+}
+
+func (s *setImpl[K]) Intersection(other Set[K]) {
+	s.m.Intersection(&other.(*setImpl[K]).m, setResolve[K])
+}
+
+func (s *setImpl[K]) Difference(other Set[K]) {
+	s.m.Difference(&other.(*setImpl[K]).m)
+}
+
+func (s *setImpl[K]) SymmetricDifference(other Set[K]) {
+	s.m.SymmetricDifference(&other.(*setImpl[K]).m)
+}
+
+func (s *setImpl[K]) Clone() Set[K] {
+	return &setImpl[K]{m: *s.m.Clone().(*mapImpl[K, struct{}])}
+}
+
+func setResolve[K any](K, struct{}, struct{}) struct{} {
+	return struct{}{}
+}
+
+// setKeys returns iterator over keys of items.
+func setKeys[K any](seq iter.Seq2[K, struct{}]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range seq {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package btree
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/udovin/algo/ordered"
+)
+
+var (
+	// ErrNotSorted is the same error as ordered.ErrNotSorted.
+	ErrNotSorted = ordered.ErrNotSorted
+	// ErrDuplicateKey is the same error as ordered.ErrDuplicateKey.
+	ErrDuplicateKey = ordered.ErrDuplicateKey
+)
+
+// FromSorted creates map from keys sorted in ascending order and
+// corresponding values.
+//
+// Keys should be unique, otherwise FromSorted will return error.
+// FromSorted takes O(n) time and fills nodes as much as possible.
+func FromSorted[K, V any](less func(K, K) bool, keys []K, values []V) (Map[K, V], error) {
+	return FromSortedWithOptions(less, keys, values, MapOptions{})
+}
+
+// FromSortedWithOptions creates map with specified options from keys
+// sorted in ascending order and corresponding values.
+func FromSortedWithOptions[K, V any](
+	less func(K, K) bool, keys []K, values []V, options MapOptions,
+) (Map[K, V], error) {
+	if len(keys) != len(values) {
+		panic("keys and values have different lengths")
+	}
+	for i := 1; i < len(keys); i++ {
+		if err := checkSorted(less, keys[i-1], keys[i]); err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+	}
+	m := newMapImpl[K, V](less, options)
+	b := mapBuilder[K, V]{keys: keys, values: values}
+	b.build(&m)
+	return &m, nil
+}
+
+// FromSortedSeq creates map from sequence of items sorted by key
+// in ascending order.
+//
+// Keys should be unique, otherwise FromSortedSeq will return error.
+// FromSortedSeq takes O(n) time and fills nodes as much as possible.
+func FromSortedSeq[K, V any](less func(K, K) bool, seq iter.Seq2[K, V]) (Map[K, V], error) {
+	return FromSortedSeqWithOptions(less, seq, MapOptions{})
+}
+
+// FromSortedSeqWithOptions creates map with specified options from
+// sequence of items sorted by key in ascending order.
+func FromSortedSeqWithOptions[K, V any](
+	less func(K, K) bool, seq iter.Seq2[K, V], options MapOptions,
+) (Map[K, V], error) {
+	b := mapBuilder[K, V]{}
+	for key, value := range seq {
+		if len(b.keys) > 0 {
+			if err := checkSorted(less, b.keys[len(b.keys)-1], key); err != nil {
+				return nil, fmt.Errorf("key %d: %w", len(b.keys), err)
+			}
+		}
+		b.append(key, value)
+	}
+	m := newMapImpl[K, V](less, options)
+	b.build(&m)
+	return &m, nil
+}
+
+func checkSorted[K any](less func(K, K) bool, prev, key K) error {
+	if less(key, prev) {
+		return ErrNotSorted
+	}
+	if !less(prev, key) {
+		return ErrDuplicateKey
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package btree
+
+import (
+	"errors"
+	"iter"
+	"sync"
+)
+
+var (
+	// ErrConflict means that transaction modifies key that is modified
+	// by other transaction committed after beginning of transaction.
+	ErrConflict = errors.New("transaction conflict")
+	// ErrTxDone means that transaction is already committed or rolled
+	// back.
+	ErrTxDone = errors.New("transaction is done")
+)
+
+// TxMap represents map with snapshot isolation transactions.
+//
+// TxMap is safe for concurrent use. Lock is held only during beginning
+// and commit of transactions, so transactions can work concurrently.
+type TxMap[K, V any] interface {
+	// Begin starts new transaction that sees snapshot of map.
+	//
+	// Transaction should be finished with Commit or Rollback.
+	Begin() Txn[K, V]
+}
+
+// Txn represents transaction of TxMap.
+//
+// Transaction sees snapshot of map at the moment of beginning with its
+// own modifications. Transaction is not safe for concurrent use.
+type Txn[K, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V)
+	Delete(key K)
+	Len() int
+	Iter() MapIter[K, V]
+	// All returns iterator over all items in ascending order.
+	All() iter.Seq2[K, V]
+	// Commit atomically applies modifications of transaction to map.
+	//
+	// If other transaction that is committed after beginning of this
+	// transaction modified some of the same keys, then modifications
+	// are discarded and ErrConflict is returned.
+	Commit() error
+	// Rollback discards modifications of transaction.
+	Rollback()
+}
+
+func NewTxMap[K, V any](less func(K, K) bool) TxMap[K, V] {
+	return NewTxMapWithOptions[K, V](less, MapOptions{})
+}
+
+// NewTxMapWithOptions creates new transactional map with specified
+// options of underlying map.
+func NewTxMapWithOptions[K, V any](less func(K, K) bool, options MapOptions) TxMap[K, V] {
+	return &txMapImpl[K, V]{
+		m:      newMapImpl[K, V](less, options),
+		active: map[uint64]int{},
+	}
+}
+
+// txCommit represents keys modified by committed transaction.
+type txCommit[K any] struct {
+	version uint64
+	keys    Set[K]
+}
+
+type txMapImpl[K, V any] struct {
+	mutex sync.Mutex
+	m     mapImpl[K, V]
+	// version represents amount of commits that modified map.
+	version uint64
+	// commits contains commits that can conflict with active
+	// transactions.
+	commits []txCommit[K]
+	// active contains amount of active transactions by versions
+	// of their snapshots.
+	active map[uint64]int
+}
+
+func (m *txMapImpl[K, V]) Begin() Txn[K, V] {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.active[m.version]++
+	return &txnImpl[K, V]{
+		tx:      m,
+		m:       m.m.Clone().(*mapImpl[K, V]),
+		writes:  NewSet[K](m.m.less),
+		version: m.version,
+	}
+}
+
+// commit applies modifications of transaction or returns ErrConflict.
+func (m *txMapImpl[K, V]) commit(t *txnImpl[K, V]) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	defer m.finish(t.version)
+	if t.writes.Len() == 0 {
+		return nil
+	}
+	for _, c := range m.commits {
+		if c.version <= t.version {
+			continue
+		}
+		for key := range t.writes.All() {
+			if c.keys.Has(key) {
+				return ErrConflict
+			}
+		}
+	}
+	for key := range t.writes.All() {
+		if value, ok := t.m.Get(key); ok {
+			m.m.Set(key, value)
+		} else {
+			m.m.Delete(key)
+		}
+	}
+	m.version++
+	m.commits = append(m.commits, txCommit[K]{version: m.version, keys: t.writes})
+	return nil
+}
+
+func (m *txMapImpl[K, V]) rollback(t *txnImpl[K, V]) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.finish(t.version)
+}
+
+// finish removes transaction from active transactions and forgets
+// commits that can not conflict with remaining transactions.
+func (m *txMapImpl[K, V]) finish(version uint64) {
+	m.active[version]--
+	if m.active[version] == 0 {
+		delete(m.active, version)
+	}
+	oldest := m.version
+	for v := range m.active {
+		oldest = min(oldest, v)
+	}
+	i := 0
+	for i < len(m.commits) && m.commits[i].version <= oldest {
+		i++
+	}
+	clear(m.commits[:i])
+	m.commits = m.commits[i:]
+}
+
+type txnImpl[K, V any] struct {
+	tx *txMapImpl[K, V]
+	// m contains snapshot with modifications of transaction.
+	m *mapImpl[K, V]
+	// writes contains keys modified by transaction.
+	writes  Set[K]
+	version uint64
+	done    bool
+}
+
+func (t *txnImpl[K, V]) Get(key K) (V, bool) {
+	t.check()
+	return t.m.Get(key)
+}
+
+func (t *txnImpl[K, V]) Set(key K, value V) {
+	t.check()
+	t.m.Set(key, value)
+	t.writes.Add(key)
+}
+
+// Delete removes key from transaction.
+//
+// Deletion of missing key is also a modification, because other
+// transaction can add the same key concurrently.
+func (t *txnImpl[K, V]) Delete(key K) {
+	t.check()
+	t.m.Delete(key)
+	t.writes.Add(key)
+}
+
+func (t *txnImpl[K, V]) Len() int {
+	t.check()
+	return t.m.Len()
+}
+
+func (t *txnImpl[K, V]) Iter() MapIter[K, V] {
+	t.check()
+	return &txnIter[K, V]{mapIter: &mapIter[K, V]{m: t.m}, t: t}
+}
+
+func (t *txnImpl[K, V]) All() iter.Seq2[K, V] {
+	t.check()
+	return t.m.All()
+}
+
+func (t *txnImpl[K, V]) Commit() error {
+	if t.done {
+		return ErrTxDone
+	}
+	t.done = true
+	return t.tx.commit(t)
+}
+
+func (t *txnImpl[K, V]) Rollback() {
+	if t.done {
+		return
+	}
+	t.done = true
+	t.tx.rollback(t)
+}
+
+// check panics if transaction is done.
+func (t *txnImpl[K, V]) check() {
+	if t.done {
+		panic(ErrTxDone.Error())
+	}
+}
+
+// txnIter represents iterator that tracks modifications of transaction.
+type txnIter[K, V any] struct {
+	*mapIter[K, V]
+	t *txnImpl[K, V]
+}
+
+func (it *txnIter[K, V]) SetValue(value V) {
+	it.t.check()
+	it.mapIter.SetValue(value)
+	it.t.writes.Add(it.Key())
+}
+
+func (it *txnIter[K, V]) Delete() bool {
+	it.t.check()
+	if !it.seeked {
+		return false
+	}
+	it.t.writes.Add(it.Key())
+	return it.mapIter.Delete()
+}
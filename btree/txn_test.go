@@ -0,0 +1,204 @@
+package btree
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestTxn(t *testing.T) {
+	m := NewTxMap[int, int](intLess)
+	t1 := m.Begin()
+	for i := 0; i < 100; i++ {
+		t1.Set(i, i)
+	}
+	t2 := m.Begin()
+	if v := t2.Len(); v != 0 {
+		t.Fatalf("Expected len = %d, got %d", 0, v)
+	}
+	if err := t1.Commit(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	if _, ok := t2.Get(0); ok {
+		t.Fatal("Transaction should see its snapshot")
+	}
+	t2.Rollback()
+	t3 := m.Begin()
+	if v := t3.Len(); v != 100 {
+		t.Fatalf("Expected len = %d, got %d", 100, v)
+	}
+	t3.Delete(0)
+	t3.Set(1, -1)
+	if _, ok := t3.Get(0); ok {
+		t.Fatalf("Key %d should not exist", 0)
+	}
+	t3.Rollback()
+	t4 := m.Begin()
+	defer t4.Rollback()
+	if v, ok := t4.Get(1); !ok || v != 1 {
+		t.Fatalf("Expected value = %d, got %d", 1, v)
+	}
+	count := 0
+	for k, v := range t4.All() {
+		if k != v {
+			t.Fatalf("Invalid item (%d, %d)", k, v)
+		}
+		count++
+	}
+	if count != 100 {
+		t.Fatalf("Expected %d items, got %d", 100, count)
+	}
+}
+
+func TestTxnConflict(t *testing.T) {
+	m := NewTxMap[int, int](intLess)
+	t1 := m.Begin()
+	t2 := m.Begin()
+	t3 := m.Begin()
+	t4 := m.Begin()
+	t1.Set(1, 1)
+	t2.Set(1, 2)
+	t3.Set(2, 3)
+	t4.Delete(1)
+	if err := t1.Commit(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	if err := t2.Commit(); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected error %v, got %v", ErrConflict, err)
+	}
+	if err := t3.Commit(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	// Deletion of missing key conflicts with concurrent insertion.
+	if err := t4.Commit(); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected error %v, got %v", ErrConflict, err)
+	}
+	if err := t4.Commit(); !errors.Is(err, ErrTxDone) {
+		t.Fatalf("Expected error %v, got %v", ErrTxDone, err)
+	}
+	t5 := m.Begin()
+	if v, ok := t5.Get(1); !ok || v != 1 {
+		t.Fatalf("Expected value = %d, got %d", 1, v)
+	}
+	if v, ok := t5.Get(2); !ok || v != 3 {
+		t.Fatalf("Expected value = %d, got %d", 3, v)
+	}
+	if err := t5.Commit(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	if v := len(m.(*txMapImpl[int, int]).commits); v != 0 {
+		t.Fatalf("Expected %d commits, got %d", 0, v)
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Expected panic")
+		}
+	}()
+	t5.Get(1)
+}
+
+func TestTxnIter(t *testing.T) {
+	m := NewTxMap[int, int](intLess)
+	t1 := m.Begin()
+	for i := 0; i < 100; i++ {
+		t1.Set(i, i)
+	}
+	if err := t1.Commit(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	t2 := m.Begin()
+	t3 := m.Begin()
+	t3.Set(99, 0)
+	it := t2.Iter()
+	for ok := it.First(); ok; {
+		if it.Key()%2 == 0 {
+			ok = it.Delete()
+		} else {
+			it.SetValue(-it.Key())
+			ok = it.Next()
+		}
+	}
+	// Deletion through ended iterator is not a modification.
+	writes := t2.(*txnImpl[int, int]).writes.Len()
+	if it.Delete() {
+		t.Fatal("Delete should return false")
+	}
+	if v := t2.(*txnImpl[int, int]).writes.Len(); v != writes {
+		t.Fatalf("Expected %d writes, got %d", writes, v)
+	}
+	if err := t2.Commit(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	if err := t3.Commit(); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected error %v, got %v", ErrConflict, err)
+	}
+	t4 := m.Begin()
+	defer t4.Rollback()
+	if v := t4.Len(); v != 50 {
+		t.Fatalf("Expected len = %d, got %d", 50, v)
+	}
+	for k, v := range t4.All() {
+		if k%2 == 0 || v != -k {
+			t.Fatalf("Invalid item (%d, %d)", k, v)
+		}
+	}
+}
+
+func TestTxnConcurrent(t *testing.T) {
+	m := NewTxMapWithOptions[int, int](intLess, MapOptions{Degree: 2})
+	n := 100
+	init := m.Begin()
+	for i := 0; i < n; i++ {
+		init.Set(i, 100)
+	}
+	if err := init.Commit(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for i := 0; i < 200; i++ {
+				tx := m.Begin()
+				if rnd.Intn(4) == 0 {
+					// Snapshot should always be consistent.
+					sum := 0
+					for _, v := range tx.All() {
+						sum += v
+					}
+					tx.Rollback()
+					if sum != n*100 {
+						t.Errorf("Expected sum = %d, got %d", n*100, sum)
+					}
+					continue
+				}
+				from, to := rnd.Intn(n), rnd.Intn(n)
+				x, _ := tx.Get(from)
+				y, _ := tx.Get(to)
+				if from != to {
+					tx.Set(from, x-1)
+					tx.Set(to, y+1)
+				}
+				if err := tx.Commit(); err != nil && !errors.Is(err, ErrConflict) {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+	tx := m.Begin()
+	defer tx.Rollback()
+	sum := 0
+	for _, v := range tx.All() {
+		sum += v
+	}
+	if sum != n*100 {
+		t.Fatalf("Expected sum = %d, got %d", n*100, sum)
+	}
+	if v := len(m.(*txMapImpl[int, int]).commits); v != 0 {
+		t.Fatalf("Expected %d commits, got %d", 0, v)
+	}
+}
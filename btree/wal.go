@@ -0,0 +1,144 @@
+package btree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// SyncPolicy represents policy of syncing write-ahead log to disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs log after each record.
+	SyncAlways SyncPolicy = iota
+	// SyncBatch syncs log after batch of records.
+	SyncBatch
+	// SyncNone never syncs log explicitly, so durability depends on
+	// operating system. Records are still written to log immediately.
+	SyncNone
+)
+
+// walRecordHeaderSize represents size of checksum and size of record.
+const walRecordHeaderSize = 8
+
+// wal represents write-ahead log.
+//
+// Each record is prefixed with checksum and size of payload. Torn or
+// corrupted record at the end of log is discarded on replay together
+// with all following bytes.
+type wal struct {
+	file      *os.File
+	policy    SyncPolicy
+	batchSize int
+	// size represents size of log in bytes.
+	size int64
+	// unsynced represents amount of records that are written after
+	// last sync.
+	unsynced int
+	buf      []byte
+}
+
+func openWAL(path string, policy SyncPolicy, batchSize int) (*wal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &wal{file: file, policy: policy, batchSize: max(batchSize, 1)}, nil
+}
+
+// replay calls fn for payload of each valid record and truncates log
+// after the last valid record.
+func (w *wal) replay(fn func(payload []byte) error) error {
+	stat, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(io.NewSectionReader(w.file, 0, stat.Size()))
+	var header [walRecordHeaderSize]byte
+	offset := int64(0)
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			break
+		}
+		size := int64(binary.LittleEndian.Uint32(header[4:]))
+		if size > stat.Size()-offset-walRecordHeaderSize {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			break
+		}
+		crc := crc32.Update(crc32.ChecksumIEEE(header[4:]), crc32.IEEETable, payload)
+		if binary.LittleEndian.Uint32(header[:]) != crc {
+			break
+		}
+		if err := fn(payload); err != nil {
+			return err
+		}
+		offset += walRecordHeaderSize + size
+	}
+	w.size = offset
+	if offset == stat.Size() {
+		return nil
+	}
+	if err := w.file.Truncate(offset); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// append writes record with specified payload and syncs log according
+// to policy.
+func (w *wal) append(payload []byte) error {
+	w.buf = binary.LittleEndian.AppendUint32(w.buf[:0], 0)
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, uint32(len(payload)))
+	w.buf = append(w.buf, payload...)
+	binary.LittleEndian.PutUint32(w.buf, crc32.ChecksumIEEE(w.buf[4:]))
+	if _, err := w.file.WriteAt(w.buf, w.size); err != nil {
+		return err
+	}
+	w.size += int64(len(w.buf))
+	w.unsynced++
+	switch w.policy {
+	case SyncAlways:
+		return w.sync()
+	case SyncBatch:
+		if w.unsynced >= w.batchSize {
+			return w.sync()
+		}
+	}
+	return nil
+}
+
+// sync syncs all written records.
+func (w *wal) sync() error {
+	if w.unsynced == 0 {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.unsynced = 0
+	return nil
+}
+
+// reset removes all records from log.
+func (w *wal) reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.size = 0
+	w.unsynced = 0
+	return nil
+}
+
+func (w *wal) close() error {
+	return errors.Join(w.sync(), w.file.Close())
+}
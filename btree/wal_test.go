@@ -0,0 +1,81 @@
+package btree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func testReplayWAL(t *testing.T, path string) (*wal, []string) {
+	w, err := openWAL(path, SyncAlways, 1)
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	var payloads []string
+	if err := w.replay(func(payload []byte) error {
+		payloads = append(payloads, string(payload))
+		return nil
+	}); err != nil {
+		t.Fatal("Error:", err)
+	}
+	return w, payloads
+}
+
+func TestWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal")
+	w, payloads := testReplayWAL(t, path)
+	if len(payloads) != 0 {
+		t.Fatalf("Expected %d records, got %d", 0, len(payloads))
+	}
+	var expected []string
+	var ends []int64
+	for i := 0; i < 10; i++ {
+		payload := fmt.Sprint("record", i)
+		if err := w.append([]byte(payload)); err != nil {
+			t.Fatal("Error:", err)
+		}
+		expected = append(expected, payload)
+		ends = append(ends, w.size)
+	}
+	if err := w.close(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	w, payloads = testReplayWAL(t, path)
+	if !slices.Equal(payloads, expected) {
+		t.Fatalf("Invalid records: %v", payloads)
+	}
+	_ = w.close()
+	// Corrupt payload of record 5.
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal("Error:", err)
+	}
+	if _, err := file.WriteAt([]byte{'x'}, ends[4]+walRecordHeaderSize); err != nil {
+		t.Fatal("Error:", err)
+	}
+	_ = file.Close()
+	w, payloads = testReplayWAL(t, path)
+	if !slices.Equal(payloads, expected[:5]) {
+		t.Fatalf("Invalid records: %v", payloads)
+	}
+	if w.size != ends[4] {
+		t.Fatalf("Expected size = %d, got %d", ends[4], w.size)
+	}
+	if err := w.append([]byte("last")); err != nil {
+		t.Fatal("Error:", err)
+	}
+	_ = w.close()
+	w, payloads = testReplayWAL(t, path)
+	defer func() { _ = w.close() }()
+	if !slices.Equal(payloads, append(expected[:5], "last")) {
+		t.Fatalf("Invalid records: %v", payloads)
+	}
+	if err := w.reset(); err != nil {
+		t.Fatal("Error:", err)
+	}
+	if stat, err := os.Stat(path); err != nil || stat.Size() != 0 {
+		t.Fatalf("Expected empty log")
+	}
+}
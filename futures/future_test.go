@@ -2,6 +2,7 @@ package futures
 
 import (
 	"context"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -97,11 +98,14 @@ func TestCall(t *testing.T) {
 		if _, err := future.Get(context.Background()); err == nil {
 			t.Fatal("Expected error")
 		} else {
-			if m := err.Error(); m != "panic: <nil>" {
-				t.Fatalf("Expected %q but got %q", "panic: <nil>", m)
+			expected := "panic: " + (&runtime.PanicNilError{}).Error()
+			if m := err.Error(); m != expected {
+				t.Fatalf("Expected %q but got %q", expected, m)
 			}
-			if _, ok := err.(PanicError); !ok {
+			if p, ok := err.(PanicError); !ok {
 				t.Fatal("Expected PanicError")
+			} else if _, ok := p.Value.(*runtime.PanicNilError); !ok {
+				t.Fatal("Expected PanicNilError")
 			}
 		}
 	}
@@ -0,0 +1,10 @@
+package ordered
+
+import "errors"
+
+var (
+	// ErrNotSorted means that keys are not sorted in ascending order.
+	ErrNotSorted = errors.New("keys are not sorted")
+	// ErrDuplicateKey means that keys contain equal keys.
+	ErrDuplicateKey = errors.New("duplicate key")
+)
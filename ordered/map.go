@@ -0,0 +1,46 @@
+// Package ordered defines common interface of ordered maps.
+package ordered
+
+import "iter"
+
+// Iter represents iterator over ordered map.
+type Iter[K, V any] interface {
+	// Next moves iterator forward.
+	Next() bool
+	// Prev moves iterator backward.
+	Prev() bool
+	// First moves iterator to first item with smallest key, or
+	// returns false if map is empty.
+	First() bool
+	// Last moves iterator to last item with largest key, or
+	// returns false if map is empty.
+	Last() bool
+	// Seek moves iterator to item with item.key >= key, or
+	// returns false if there is no such key.
+	Seek(key K) bool
+	// SeekPrev moves iterator to item with item.key <= key, or
+	// returns false if there is no such key.
+	SeekPrev(key K) bool
+	// Key returns current item key.
+	Key() K
+	// Value returns current item value.
+	Value() V
+	// SetValue sets value of current item.
+	SetValue(value V)
+}
+
+// Map represents map with keys in ascending order.
+type Map[K, V any] interface {
+	// Get returns value by specified key.
+	Get(key K) (V, bool)
+	// Set updates value by specified key.
+	Set(key K, value V)
+	// Delete removes specified key.
+	Delete(key K)
+	// Len returns amount of items in map.
+	Len() int
+	// All returns iterator over all items in ascending order.
+	All() iter.Seq2[K, V]
+	// Iter returns new iterator over map.
+	Iter() Iter[K, V]
+}
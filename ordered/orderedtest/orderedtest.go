@@ -0,0 +1,260 @@
+// Package orderedtest implements conformance tests for ordered maps.
+package orderedtest
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/udovin/algo/ordered"
+)
+
+// TestMap runs conformance tests for ordered map implementation.
+//
+// Function newMap should create new empty map with integer keys in
+// ascending order.
+func TestMap(t *testing.T, newMap func() ordered.Map[int, int]) {
+	t.Run("Empty", func(t *testing.T) {
+		testEmpty(t, newMap())
+	})
+	t.Run("SetGetDelete", func(t *testing.T) {
+		testSetGetDelete(t, newMap())
+	})
+	t.Run("Iter", func(t *testing.T) {
+		testIter(t, newMap())
+	})
+	t.Run("Seek", func(t *testing.T) {
+		testSeek(t, newMap())
+	})
+	t.Run("SetValue", func(t *testing.T) {
+		testSetValue(t, newMap())
+	})
+	t.Run("Random", func(t *testing.T) {
+		testRandom(t, newMap())
+	})
+}
+
+func testEmpty(t *testing.T, m ordered.Map[int, int]) {
+	if v := m.Len(); v != 0 {
+		t.Fatalf("Expected len = %d, got %d", 0, v)
+	}
+	if _, ok := m.Get(0); ok {
+		t.Fatalf("Key %d should not exist", 0)
+	}
+	m.Delete(0)
+	for k := range m.All() {
+		t.Fatalf("Unexpected key %d", k)
+	}
+	it := m.Iter()
+	if it.First() || it.Last() || it.Next() || it.Prev() {
+		t.Fatal("Iter should be ended")
+	}
+	if it.Seek(0) || it.SeekPrev(0) {
+		t.Fatal("Iter should be ended")
+	}
+}
+
+func testSetGetDelete(t *testing.T, m ordered.Map[int, int]) {
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+		if v := m.Len(); v != i+1 {
+			t.Fatalf("Expected len = %d, got %d", i+1, v)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if v, ok := m.Get(i); !ok || v != i {
+			t.Fatalf("Expected value = %d, got %d", i, v)
+		}
+	}
+	for i := 0; i < n; i++ {
+		m.Set(i, -i)
+	}
+	if v := m.Len(); v != n {
+		t.Fatalf("Expected len = %d, got %d", n, v)
+	}
+	for i := 0; i < n; i++ {
+		if v, ok := m.Get(i); !ok || v != -i {
+			t.Fatalf("Expected value = %d, got %d", -i, v)
+		}
+	}
+	if _, ok := m.Get(n); ok {
+		t.Fatalf("Key %d should not exist", n)
+	}
+	for i := 0; i < n; i += 2 {
+		m.Delete(i)
+		m.Delete(i)
+	}
+	if v := m.Len(); v != n/2 {
+		t.Fatalf("Expected len = %d, got %d", n/2, v)
+	}
+	for i := 0; i < n; i++ {
+		if _, ok := m.Get(i); ok != (i%2 == 1) {
+			t.Fatalf("Invalid existence of key %d", i)
+		}
+	}
+	for i := 1; i < n; i += 2 {
+		m.Delete(i)
+	}
+	if v := m.Len(); v != 0 {
+		t.Fatalf("Expected len = %d, got %d", 0, v)
+	}
+}
+
+func testIter(t *testing.T, m ordered.Map[int, int]) {
+	n := 1000
+	for _, i := range rand.New(rand.NewSource(42)).Perm(n) {
+		m.Set(i, i)
+	}
+	it := m.Iter()
+	for i := 0; i < n; i++ {
+		if !it.Next() {
+			t.Fatal("Unexpected end of iter")
+		}
+		if v := it.Key(); v != i {
+			t.Fatalf("Expected key = %d, got %d", i, v)
+		}
+		if v := it.Value(); v != i {
+			t.Fatalf("Expected value = %d, got %d", i, v)
+		}
+	}
+	if it.Next() {
+		t.Fatal("Iter should be ended")
+	}
+	it = m.Iter()
+	for i := n - 1; i >= 0; i-- {
+		if !it.Prev() {
+			t.Fatal("Unexpected end of iter")
+		}
+		if v := it.Key(); v != i {
+			t.Fatalf("Expected key = %d, got %d", i, v)
+		}
+	}
+	if it.Prev() {
+		t.Fatal("Iter should be ended")
+	}
+	if !it.First() || it.Key() != 0 || it.Prev() {
+		t.Fatal("Invalid first item")
+	}
+	if !it.Last() || it.Key() != n-1 || it.Next() {
+		t.Fatal("Invalid last item")
+	}
+	if !it.First() || !it.Next() || !it.Next() || !it.Prev() || it.Key() != 1 {
+		t.Fatal("Invalid item after changing direction")
+	}
+	i := 0
+	for k, v := range m.All() {
+		if k != i || v != i {
+			t.Fatalf("Expected key = %d, got %d", i, k)
+		}
+		i++
+	}
+	if i != n {
+		t.Fatalf("Expected %d items, got %d", n, i)
+	}
+}
+
+func testSeek(t *testing.T, m ordered.Map[int, int]) {
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(i*2, i)
+	}
+	it := m.Iter()
+	for i := -1; i < 2*n-1; i++ {
+		next := i + i&1
+		if !it.Seek(i) {
+			t.Fatalf("Unable to seek %d", i)
+		}
+		if v := it.Key(); v != next {
+			t.Fatalf("Expected key = %d, got %d", next, v)
+		}
+		if next+2 < 2*n {
+			if !it.Next() || it.Key() != next+2 {
+				t.Fatalf("Invalid key after %d", next)
+			}
+		}
+		if i < 0 {
+			if it.SeekPrev(i) {
+				t.Fatal("Iter should be ended")
+			}
+			continue
+		}
+		prev := i - i&1
+		if !it.SeekPrev(i) {
+			t.Fatalf("Unable to seek %d", i)
+		}
+		if v := it.Key(); v != prev {
+			t.Fatalf("Expected key = %d, got %d", prev, v)
+		}
+		if prev > 0 {
+			if !it.Prev() || it.Key() != prev-2 {
+				t.Fatalf("Invalid key before %d", prev)
+			}
+		}
+	}
+	if it.Seek(2 * n) {
+		t.Fatal("Iter should be ended")
+	}
+	if !it.SeekPrev(2*n) || it.Key() != 2*n-2 {
+		t.Fatal("Invalid last key")
+	}
+}
+
+func testSetValue(t *testing.T, m ordered.Map[int, int]) {
+	n := 1000
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	for it := m.Iter(); it.Next(); {
+		it.SetValue(it.Key() * 2)
+	}
+	for i := 0; i < n; i++ {
+		if v, ok := m.Get(i); !ok || v != i*2 {
+			t.Fatalf("Expected value = %d, got %d", i*2, v)
+		}
+	}
+}
+
+func testRandom(t *testing.T, m ordered.Map[int, int]) {
+	rnd := rand.New(rand.NewSource(42))
+	var keys, values []int
+	for i := 0; i < 20000; i++ {
+		k := rnd.Intn(2000)
+		j, ok := slices.BinarySearch(keys, k)
+		if rnd.Intn(3) == 0 {
+			m.Delete(k)
+			if ok {
+				keys = slices.Delete(keys, j, j+1)
+				values = slices.Delete(values, j, j+1)
+			}
+		} else {
+			m.Set(k, i)
+			if ok {
+				values[j] = i
+			} else {
+				keys = slices.Insert(keys, j, k)
+				values = slices.Insert(values, j, i)
+			}
+		}
+		if v := m.Len(); v != len(keys) {
+			t.Fatalf("Expected len = %d, got %d", len(keys), v)
+		}
+		j, ok = slices.BinarySearch(keys, k)
+		if v, found := m.Get(k); found != ok || ok && v != values[j] {
+			t.Fatalf("Invalid value of key %d", k)
+		}
+		if i%1000 != 0 {
+			continue
+		}
+		j = 0
+		for k, v := range m.All() {
+			if j >= len(keys) || k != keys[j] || v != values[j] {
+				t.Fatalf("Invalid item (%d, %d)", k, v)
+			}
+			j++
+		}
+		if j != len(keys) {
+			t.Fatalf("Expected %d items, got %d", len(keys), j)
+		}
+	}
+}
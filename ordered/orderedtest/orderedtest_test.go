@@ -0,0 +1,124 @@
+package orderedtest
+
+import (
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/udovin/algo/ordered"
+)
+
+// sliceMap represents the simplest ordered map for testing of suite.
+type sliceMap struct {
+	keys   []int
+	values []int
+}
+
+func (m *sliceMap) Get(key int) (int, bool) {
+	if i, ok := slices.BinarySearch(m.keys, key); ok {
+		return m.values[i], true
+	}
+	return 0, false
+}
+
+func (m *sliceMap) Set(key int, value int) {
+	i, ok := slices.BinarySearch(m.keys, key)
+	if ok {
+		m.values[i] = value
+		return
+	}
+	m.keys = slices.Insert(m.keys, i, key)
+	m.values = slices.Insert(m.values, i, value)
+}
+
+func (m *sliceMap) Delete(key int) {
+	if i, ok := slices.BinarySearch(m.keys, key); ok {
+		m.keys = slices.Delete(m.keys, i, i+1)
+		m.values = slices.Delete(m.values, i, i+1)
+	}
+}
+
+func (m *sliceMap) Len() int {
+	return len(m.keys)
+}
+
+func (m *sliceMap) All() iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for i := range m.keys {
+			if !yield(m.keys[i], m.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (m *sliceMap) Iter() ordered.Iter[int, int] {
+	return &sliceIter{m: m, i: -1}
+}
+
+type sliceIter struct {
+	m *sliceMap
+	i int
+}
+
+func (it *sliceIter) move(i int) bool {
+	if i < 0 || i >= len(it.m.keys) {
+		it.i = -1
+		return false
+	}
+	it.i = i
+	return true
+}
+
+func (it *sliceIter) Next() bool {
+	if it.i < 0 {
+		return it.First()
+	}
+	return it.move(it.i + 1)
+}
+
+func (it *sliceIter) Prev() bool {
+	if it.i < 0 {
+		return it.Last()
+	}
+	return it.move(it.i - 1)
+}
+
+func (it *sliceIter) First() bool {
+	return it.move(0)
+}
+
+func (it *sliceIter) Last() bool {
+	return it.move(len(it.m.keys) - 1)
+}
+
+func (it *sliceIter) Seek(key int) bool {
+	i, _ := slices.BinarySearch(it.m.keys, key)
+	return it.move(i)
+}
+
+func (it *sliceIter) SeekPrev(key int) bool {
+	i, ok := slices.BinarySearch(it.m.keys, key)
+	if !ok {
+		i--
+	}
+	return it.move(i)
+}
+
+func (it *sliceIter) Key() int {
+	return it.m.keys[it.i]
+}
+
+func (it *sliceIter) Value() int {
+	return it.m.values[it.i]
+}
+
+func (it *sliceIter) SetValue(value int) {
+	it.m.values[it.i] = value
+}
+
+func TestSliceMap(t *testing.T) {
+	TestMap(t, func() ordered.Map[int, int] {
+		return &sliceMap{}
+	})
+}